@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gabkaclassic/marktuator/pkg/md"
+	"github.com/gabkaclassic/marktuator/pkg/url_validator"
+)
+
+// fixLinks rewrites links that permanently moved to their new location and
+// upgrades http:// links to https:// when the HTTPS URL passes the check.
+// With dryRun the changes are written to w as a unified diff instead of
+// being saved. It returns the number of rewritten destinations: links
+// sharing a reference definition are counted once.
+func fixLinks(
+	results []CheckResult,
+	client http.Client,
+	cfg url_validator.LinksValidatorConfig,
+	files map[string][]byte,
+	dryRun bool,
+	w io.Writer,
+	log *slog.Logger,
+) (int, error) {
+	edits := make(map[string][]md.URLEdit)
+	httpsChecks := make(map[string]bool)
+	type destination struct {
+		file  string
+		start int
+	}
+	fixed := make(map[destination]struct{})
+
+	for _, result := range results {
+		l := *result.link
+		if !result.ok || result.skipped || l.URLEnd == 0 || !url_validator.IsHTTPLink(l.URL) {
+			continue
+		}
+
+		newURL := ""
+		if location, moved := result.response.PermanentRedirect(); moved {
+			newURL = withFragment(location, l.URL)
+		} else if httpsURL, isHTTP := strings.CutPrefix(l.URL, "http://"); isHTTP {
+			httpsURL = "https://" + httpsURL
+			ok, checked := httpsChecks[httpsURL]
+			if !checked {
+				ok = url_validator.CheckLink(httpsURL, client, cfg, log).OK
+				httpsChecks[httpsURL] = ok
+			}
+			if ok {
+				newURL = httpsURL
+			}
+		}
+		if newURL == "" || newURL == l.URL {
+			continue
+		}
+
+		log.Debug("Rewrite link", slog.Any("link", l), slog.String("url", newURL))
+		edits[l.File] = append(edits[l.File], md.URLEdit{Link: l, URL: newURL})
+		fixed[destination{l.File, l.URLStart}] = struct{}{}
+	}
+
+	paths := make([]string, 0, len(edits))
+	for path := range edits {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		content, err := md.RewriteURLs(files[path], edits[path])
+		if err != nil {
+			return 0, err
+		}
+		if dryRun {
+			writeDiff(w, path, files[path], content)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(path, content, info.Mode().Perm()); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(fixed), nil
+}
+
+// withFragment keeps the fragment of the original link, which is never
+// sent to the server and so never appears in a redirect location.
+func withFragment(location, original string) string {
+	_, fragment, found := strings.Cut(original, "#")
+	if !found || strings.Contains(location, "#") {
+		return location
+	}
+	return location + "#" + fragment
+}
+
+// writeDiff writes the changed lines of a file as a unified diff without
+// context. Rewriting URLs never adds or removes lines, so lines are compared
+// by their number.
+func writeDiff(w io.Writer, path string, before, after []byte) {
+	oldLines := strings.Split(string(before), "\n")
+	newLines := strings.Split(string(after), "\n")
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", path, path)
+	for i := 0; i < len(oldLines); {
+		if oldLines[i] == newLines[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(oldLines) && oldLines[i] != newLines[i] {
+			i++
+		}
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", start+1, i-start, start+1, i-start)
+		for _, line := range oldLines[start:i] {
+			fmt.Fprintf(w, "-%s\n", line)
+		}
+		for _, line := range newLines[start:i] {
+			fmt.Fprintf(w, "+%s\n", line)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"github.com/gabkaclassic/marktuator/internal/config"
+	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"sort"
 	"sync"
 
 	"github.com/gabkaclassic/marktuator/pkg/logger"
@@ -12,13 +15,42 @@ import (
 	"github.com/gabkaclassic/marktuator/pkg/url_validator"
 )
 
+// Process exit codes. exitUsageError matches the code used by the flag
+// package and config.ParseConfig on invalid arguments.
+const (
+	exitOK            = 0
+	exitBrokenLinks   = 1
+	exitUsageError    = 2
+	exitInternalError = 3
+)
+
 func main() {
+	os.Exit(run())
+}
+
+func run() (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "Internal error: %v\n", r)
+			code = exitInternalError
+		}
+	}()
+
 	cfg := config.ParseConfig()
 	log := setupLogger(cfg.Logger)
 	log.Debug("Start marktuator")
 
+	if !cfg.Walker.NoIgnore {
+		ignoredURLs := md.ReadIgnoredURLs(cfg.TargetPath, log)
+		cfg.Validator.IgnoredURLs = append(cfg.Validator.IgnoredURLs, url_validator.PrepareIgnoredURLs(ignoredURLs...)...)
+	}
+
 	log.Debug("Read md files form", slog.String("filepath", cfg.TargetPath))
-	content := md.ReadMdFiles(cfg.TargetPath, log)
+	content, err := md.ReadMdFiles(cfg.TargetPath, cfg.Walker, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read %s: %v\n", cfg.TargetPath, err)
+		return exitUsageError
+	}
 
 	log.Debug("Extract links from MD files")
 	listLinks := md.ExtractLinks(content, log)
@@ -27,9 +59,32 @@ func main() {
 	client := url_validator.GetClient(cfg.Validator)
 
 	log.Debug("Check links for available")
-	checkLinks(listLinks, client, cfg.Validator, content, log)
+	results := checkLinks(listLinks, client, cfg.Validator, content, cfg.RelativeLinks, log)
+
+	log.Debug("Check link reference definitions")
+	referenceIssues := md.FindReferenceIssues(content, log)
+	for _, issue := range referenceIssues {
+		fmt.Printf("Reference issue: %s\n", issue)
+	}
+
+	printSummary(os.Stdout, results)
+
+	if cfg.Fix {
+		log.Debug("Rewrite moved links")
+		fixed, err := fixLinks(results, client, cfg.Validator, content, cfg.DryRun, os.Stdout, log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Fix failed: %v\n", err)
+			return exitInternalError
+		}
+		fmt.Printf("Fixed links: %d\n", fixed)
+	}
 
 	log.Debug("Marktuator finished")
+
+	if hasBrokenLinks(results) || len(referenceIssues) > 0 {
+		return exitBrokenLinks
+	}
+	return exitOK
 }
 
 func setupLogger(cfg logger.LoggerConfig) *slog.Logger {
@@ -44,8 +99,15 @@ func setupLogger(cfg logger.LoggerConfig) *slog.Logger {
 }
 
 type CheckResult struct {
-	link *md.Link
-	ok   bool
+	link    *md.Link
+	ok      bool
+	skipped bool
+	// response holds the HTTP check details, it is empty for links that
+	// were not requested over the network.
+	response url_validator.Result
+	// missingFragment is set for external links whose page was fetched but
+	// has no anchor matching the link fragment.
+	missingFragment bool
 }
 
 func checkLinks(
@@ -53,33 +115,61 @@ func checkLinks(
 	client http.Client,
 	cfg url_validator.LinksValidatorConfig,
 	files map[string][]byte,
+	relativeCfg md.RelativeLinksConfig,
 	log *slog.Logger,
 ) []CheckResult {
-	var resultsWg sync.WaitGroup
+	groups := groupLinks(linksList, cfg, relativeCfg)
+
+	workers := cfg.Concurrency
+	if workers <= 0 {
+		workers = url_validator.DefaultConcurrency
+	}
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+
+	groupsCh := make(chan []md.Link)
 	resultsCh := make(chan CheckResult, len(linksList))
+	var resultsWg sync.WaitGroup
 
-	for _, link := range linksList {
+	for i := 0; i < workers; i++ {
 		resultsWg.Add(1)
-		go func(l md.Link) {
+		go func() {
 			defer resultsWg.Done()
-			var ok bool
-			if l.IsRelative {
-				ok = md.CheckRelativeLink(l.URL, l.File, files, log)
-			} else {
-				ok = url_validator.CheckLink(l.URL, client, cfg, log)
+			for group := range groupsCh {
+				for _, result := range checkGroup(group, client, cfg, files, relativeCfg, log) {
+					resultsCh <- result
+				}
 			}
-			resultsCh <- CheckResult{link: &l, ok: ok}
-		}(link)
+		}()
 	}
 
+	for _, group := range groups {
+		groupsCh <- group
+	}
+	close(groupsCh)
+
 	resultsWg.Wait()
 	close(resultsCh)
 
 	var results []CheckResult
 	for result := range resultsCh {
-		if result.ok {
-			log.Debug("Link available:", slog.Any("link", result.link))
-		} else {
+		switch {
+		case result.skipped:
+			log.Debug("Link skipped:", slog.Any("link", result.link))
+		case result.ok:
+			if location, moved := result.response.PermanentRedirect(); moved {
+				printPermanentRedirect(result, location, log)
+			} else {
+				log.Debug("Link available:", slog.Any("link", result.link))
+			}
+		case result.missingFragment:
+			fmt.Printf("Link unavailable: %s (fragment not found)\n", result.link)
+			log.Info("Link unavailable:", slog.Any("link", result.link), slog.String("reason", "fragment not found"))
+		case result.response.Attempts > 0:
+			fmt.Printf("Link unavailable: %s (%s)\n", result.link, result.response)
+			log.Info("Link unavailable:", slog.Any("link", result.link), slog.String("result", result.response.String()))
+		default:
 			fmt.Printf("Link unavailable: %s\n", result.link)
 			log.Info("Link unavailable:", slog.Any("link", result.link))
 		}
@@ -89,3 +179,189 @@ func checkLinks(
 	log.Info("All links checked")
 	return results
 }
+
+func isSiteLink(url string, relativeCfg md.RelativeLinksConfig) bool {
+	_, ok := md.SiteLink(url, relativeCfg)
+	return ok
+}
+
+// printPermanentRedirect warns about a link that should be updated to the
+// location it permanently moved to.
+func printPermanentRedirect(result CheckResult, location string, log *slog.Logger) {
+	if finalURL := result.response.FinalURL(); finalURL != location {
+		fmt.Printf("Link moved permanently: %s -> %s (final destination: %s)\n", result.link, location, finalURL)
+	} else {
+		fmt.Printf("Link moved permanently: %s -> %s\n", result.link, location)
+	}
+	log.Warn("Link moved permanently:", slog.Any("link", result.link), slog.String("location", location), slog.String("final_url", result.response.FinalURL()))
+}
+
+// groupLinks groups links sharing a check target, so each unique normalized
+// URL is requested once and its result is reused for every occurrence.
+// Groups are returned in order of first occurrence.
+func groupLinks(linksList []md.Link, cfg url_validator.LinksValidatorConfig, relativeCfg md.RelativeLinksConfig) [][]md.Link {
+	indexes := make(map[string]int)
+	groups := make([][]md.Link, 0)
+
+	for _, l := range linksList {
+		url := url_validator.WithScheme(l.URL)
+		var key string
+		switch {
+		case url_validator.IsIgnoredURL(url, cfg):
+			key = "ignored\x00" + l.URL
+		case l.IsRelative:
+			key = "relative\x00" + l.File + "\x00" + l.URL
+		case isSiteLink(url, relativeCfg):
+			// Fragments of own-site links are resolved locally, so each one
+			// is checked on its own.
+			key = "site\x00" + url
+		default:
+			key = l.Kind.String() + "\x00" + url_validator.NormalizeURL(url)
+		}
+
+		index, exists := indexes[key]
+		if !exists {
+			index = len(groups)
+			indexes[key] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], l)
+	}
+
+	return groups
+}
+
+// checkGroup checks the target shared by a group of links once and fans the
+// result out to every link. Fragments of external links are verified per
+// link against the anchors of the page, fetched once for the whole group.
+func checkGroup(
+	group []md.Link,
+	client http.Client,
+	cfg url_validator.LinksValidatorConfig,
+	files map[string][]byte,
+	relativeCfg md.RelativeLinksConfig,
+	log *slog.Logger,
+) []CheckResult {
+	result := checkLink(group[0], client, cfg, files, relativeCfg, log)
+
+	var anchors map[string]struct{}
+	if result.ok && result.response.Attempts > 0 && cfg.CheckFragments && hasExternalFragment(group) {
+		var err error
+		url := url_validator.WithScheme(group[0].URL)
+		anchors, err = url_validator.FetchAnchors(url, client, cfg, log)
+		if err != nil {
+			log.Info("Anchors of page are not available", slog.String("url", url), slog.String("error", err.Error()))
+		}
+	}
+
+	results := make([]CheckResult, 0, len(group))
+	for _, l := range group {
+		linkResult := result
+		linkResult.link = &l
+		if anchors != nil && l.Fragment != "" && !url_validator.HasAnchor(url_validator.WithScheme(l.URL), l.Fragment, anchors) {
+			linkResult.ok = false
+			linkResult.missingFragment = true
+		}
+		results = append(results, linkResult)
+	}
+	return results
+}
+
+func hasExternalFragment(group []md.Link) bool {
+	for _, l := range group {
+		if l.Fragment != "" && l.Kind == md.LinkKindLink {
+			return true
+		}
+	}
+	return false
+}
+
+func checkLink(
+	l md.Link,
+	client http.Client,
+	cfg url_validator.LinksValidatorConfig,
+	files map[string][]byte,
+	relativeCfg md.RelativeLinksConfig,
+	log *slog.Logger,
+) CheckResult {
+	result := CheckResult{link: &l}
+	url := url_validator.WithScheme(l.URL)
+	siteLink, isSiteLink := md.SiteLink(url, relativeCfg)
+	switch {
+	case url_validator.IsIgnoredURL(url, cfg):
+		result.skipped = true
+	case l.IsRelative:
+		result.ok = md.CheckRelativeLink(l.URL, l.File, files, relativeCfg, log)
+	case isSiteLink:
+		result.ok = md.CheckRelativeLink(siteLink, l.File, files, relativeCfg, log)
+	case !url_validator.IsHTTPLink(url):
+		result.skipped = true
+	case l.Kind == md.LinkKindImage:
+		result.response = url_validator.CheckImageLink(url, client, cfg, log)
+		result.ok = result.response.OK
+	default:
+		result.response = url_validator.CheckLink(url, client, cfg, log)
+		result.ok = result.response.OK
+	}
+	return result
+}
+
+type linksTally struct {
+	checked int
+	ok      int
+	broken  int
+	skipped int
+}
+
+func (t *linksTally) add(result CheckResult) {
+	switch {
+	case result.skipped:
+		t.skipped++
+		return
+	case result.ok:
+		t.ok++
+	default:
+		t.broken++
+	}
+	t.checked++
+}
+
+func (t linksTally) String() string {
+	return fmt.Sprintf("checked %d, ok %d, broken %d, skipped %d", t.checked, t.ok, t.broken, t.skipped)
+}
+
+func printSummary(w io.Writer, results []CheckResult) {
+	perFile := make(map[string]*linksTally)
+	var total linksTally
+
+	for _, result := range results {
+		tally, exists := perFile[result.link.File]
+		if !exists {
+			tally = &linksTally{}
+			perFile[result.link.File] = tally
+		}
+		tally.add(result)
+		total.add(result)
+	}
+
+	files := make([]string, 0, len(perFile))
+	for file := range perFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	fmt.Fprintln(w, "Summary:")
+	for _, file := range files {
+		fmt.Fprintf(w, "  %s: %s\n", file, perFile[file])
+	}
+	fmt.Fprintf(w, "Total: %s\n", total)
+}
+
+func hasBrokenLinks(results []CheckResult) bool {
+	for _, result := range results {
+		if !result.ok && !result.skipped {
+			return true
+		}
+	}
+	return false
+}
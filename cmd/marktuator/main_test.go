@@ -1,11 +1,14 @@
 package main
 
 import (
+	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,9 +20,11 @@ var testLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Le
 
 type mockRoundTripper struct {
 	statusCodes map[string]int
+	requests    atomic.Int32
 }
 
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.requests.Add(1)
 	code := m.statusCodes[req.URL.String()]
 	if code == 0 {
 		code = 404
@@ -46,7 +51,7 @@ func TestCheckLinks_RelativeLinks(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	files := md.ReadMdFiles(tmp, testLogger)
+	files, _ := md.ReadMdFiles(tmp, md.WalkerConfig{}, testLogger)
 	links := md.ExtractLinks(files, testLogger)
 
 	if len(links) != 2 {
@@ -58,7 +63,7 @@ func TestCheckLinks_RelativeLinks(t *testing.T) {
 		Timeout:         2 * time.Second,
 	}
 
-	results := checkLinks(links, http.Client{}, cfg, files, testLogger)
+	results := checkLinks(links, http.Client{}, cfg, files, md.RelativeLinksConfig{}, testLogger)
 
 	if len(results) != 2 {
 		t.Fatalf("expected 2 results, got %d", len(results))
@@ -105,7 +110,7 @@ func TestCheckLinks_AbsoluteLinks(t *testing.T) {
 		Timeout:         1 * time.Second,
 	}
 
-	results := checkLinks(links, mockClient, cfg, nil, testLogger)
+	results := checkLinks(links, mockClient, cfg, nil, md.RelativeLinksConfig{}, testLogger)
 
 	if len(results) != 2 {
 		t.Fatalf("expected 2 results, got %d", len(results))
@@ -120,3 +125,262 @@ func TestCheckLinks_AbsoluteLinks(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckLinks_WorkerPool(t *testing.T) {
+	links := make([]md.Link, 0, 50)
+	statusCodes := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		url := "https://example.com/page" + strings.Repeat("x", i)
+		links = append(links, md.Link{File: "dummy.md", URL: url})
+		statusCodes[url] = 200
+	}
+
+	cfg := url_validator.LinksValidatorConfig{
+		AllowedStatuses: url_validator.PrepareAllowedStatuses(200),
+		Concurrency:     3,
+	}
+
+	results := checkLinks(links, http.Client{Transport: &mockRoundTripper{statusCodes: statusCodes}}, cfg, nil, md.RelativeLinksConfig{}, testLogger)
+
+	if len(results) != 50 {
+		t.Fatalf("expected 50 results, got %d", len(results))
+	}
+	if hasBrokenLinks(results) {
+		t.Errorf("expected all links to be available")
+	}
+}
+
+func TestCheckLinks_DeduplicatesURLs(t *testing.T) {
+	links := []md.Link{
+		{File: "a.md", URL: "https://example.com"},
+		{File: "b.md", URL: "https://EXAMPLE.com/#about"},
+		{File: "c.md", URL: "https://example.com:443/"},
+		{File: "c.md", URL: "//example.com"},
+		{File: "c.md", URL: "https://example.com/missing"},
+	}
+
+	transport := &mockRoundTripper{
+		statusCodes: map[string]int{
+			"https://example.com": 200,
+		},
+	}
+	cfg := url_validator.LinksValidatorConfig{
+		AllowedStatuses: url_validator.PrepareAllowedStatuses(200),
+		Method:          http.MethodGet,
+	}
+
+	results := checkLinks(links, http.Client{Transport: transport}, cfg, nil, md.RelativeLinksConfig{}, testLogger)
+
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	if got := transport.requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests for unique URLs, got %d", got)
+	}
+	for _, r := range results {
+		wantOK := r.link.URL != "https://example.com/missing"
+		if r.ok != wantOK {
+			t.Errorf("unexpected result for %s in %s: %t", r.link.URL, r.link.File, r.ok)
+		}
+	}
+}
+
+func TestCheckLinks_ExternalFragments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<h1 id="usage">Usage</h1>`))
+	}))
+	defer ts.Close()
+
+	links := []md.Link{
+		{File: "a.md", URL: ts.URL + "/docs#usage", Fragment: "usage"},
+		{File: "b.md", URL: ts.URL + "/docs#missing", Fragment: "missing"},
+		{File: "c.md", URL: ts.URL + "/docs"},
+	}
+
+	cfg := url_validator.LinksValidatorConfig{
+		AllowedStatuses: url_validator.PrepareAllowedStatuses(200),
+		CheckFragments:  true,
+	}
+
+	results := checkLinks(links, http.Client{}, cfg, nil, md.RelativeLinksConfig{}, testLogger)
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for _, r := range results {
+		wantOK := r.link.Fragment != "missing"
+		if r.ok != wantOK || r.missingFragment == wantOK {
+			t.Errorf("unexpected result for %s: ok %t, missing fragment %t", r.link.URL, r.ok, r.missingFragment)
+		}
+	}
+}
+
+func TestCheckLinks_SkipsNonHTTPLinks(t *testing.T) {
+	links := []md.Link{
+		{File: "dummy.md", Text: "Mail", URL: "mailto:test@example.com"},
+		{File: "dummy.md", Text: "Empty", URL: ""},
+	}
+
+	cfg := url_validator.LinksValidatorConfig{
+		AllowedStatuses: url_validator.PrepareAllowedStatuses(200),
+	}
+
+	results := checkLinks(links, http.Client{Transport: &mockRoundTripper{}}, cfg, nil, md.RelativeLinksConfig{}, testLogger)
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if !r.skipped {
+			t.Errorf("expected %s to be skipped", r.link)
+		}
+	}
+	if hasBrokenLinks(results) {
+		t.Errorf("skipped links must not be reported as broken")
+	}
+}
+
+func TestCheckLinks_SiteLinksAreCheckedLocally(t *testing.T) {
+	dir := t.TempDir()
+	guide := filepath.Join(dir, "guide.md")
+	if err := os.WriteFile(guide, []byte("# Install"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	links := []md.Link{
+		{File: guide, Text: "Guide", URL: "https://docs.example.com/guide"},
+		{File: guide, Text: "Missing", URL: "https://docs.example.com/missing"},
+		{File: guide, Text: "Guide", URL: "https://docs.example.com/guide#install", Fragment: "install"},
+		{File: guide, Text: "Missing fragment", URL: "https://docs.example.com/guide#missing", Fragment: "missing"},
+	}
+	cfg := url_validator.LinksValidatorConfig{
+		AllowedStatuses: url_validator.PrepareAllowedStatuses(200),
+	}
+	relativeCfg := md.RelativeLinksConfig{Root: dir, BaseURL: "https://docs.example.com"}
+	rt := &mockRoundTripper{}
+
+	results := checkLinks(links, http.Client{Transport: rt}, cfg, map[string][]byte{guide: []byte("# Install")}, relativeCfg, testLogger)
+
+	if rt.requests.Load() != 0 {
+		t.Errorf("expected no network requests, got %d", rt.requests.Load())
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if expected := r.link.Text == "Guide"; r.ok != expected {
+			t.Errorf("expected %s ok=%v, got %v", r.link, expected, r.ok)
+		}
+	}
+}
+
+func TestFixLinks(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "doc.md")
+	original := "# Doc\n\n" +
+		"[Moved](https://example.com/old#usage \"Title\") stays *as is*.\n" +
+		"[Plain](http://example.com/page) and [Broken](http://example.com/broken).\n"
+	if err := os.WriteFile(file, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := md.ReadMdFiles(dir, md.WalkerConfig{}, testLogger)
+	links := md.ExtractLinks(files, testLogger)
+	results := make([]CheckResult, 0, len(links))
+	for _, l := range links {
+		result := CheckResult{link: &l, ok: l.Text != "Broken"}
+		if l.Text == "Moved" {
+			result.response.Redirects = []url_validator.Redirect{
+				{From: "https://example.com/old", To: "https://example.com/new", StatusCode: http.StatusMovedPermanently},
+			}
+		}
+		results = append(results, result)
+	}
+
+	client := http.Client{Transport: &mockRoundTripper{statusCodes: map[string]int{"https://example.com/page": 200}}}
+	cfg := url_validator.LinksValidatorConfig{AllowedStatuses: url_validator.PrepareAllowedStatuses(200)}
+
+	var diff strings.Builder
+	fixed, err := fixLinks(results, client, cfg, files, true, &diff, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 2 {
+		t.Errorf("expected 2 fixed links, got %d", fixed)
+	}
+	wantDiff := "--- " + file + "\n+++ " + file + "\n" +
+		"@@ -3,2 +3,2 @@\n" +
+		"-[Moved](https://example.com/old#usage \"Title\") stays *as is*.\n" +
+		"-[Plain](http://example.com/page) and [Broken](http://example.com/broken).\n" +
+		"+[Moved](https://example.com/new#usage \"Title\") stays *as is*.\n" +
+		"+[Plain](https://example.com/page) and [Broken](http://example.com/broken).\n"
+	if diff.String() != wantDiff {
+		t.Errorf("unexpected diff:\n%s", diff.String())
+	}
+	if content, _ := os.ReadFile(file); string(content) != original {
+		t.Errorf("dry run must not change the file")
+	}
+
+	if _, err := fixLinks(results, client, cfg, files, false, &diff, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(file)
+	want := strings.NewReplacer("https://example.com/old", "https://example.com/new", "http://example.com/page", "https://example.com/page").Replace(original)
+	if string(content) != want {
+		t.Errorf("unexpected content:\n%s", content)
+	}
+}
+
+func TestFixLinks_SharedDefinition(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "doc.md")
+	original := "[One][page] and [Two][page].\n\n[page]: http://example.com/page\n"
+	if err := os.WriteFile(file, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := md.ReadMdFiles(dir, md.WalkerConfig{}, testLogger)
+	links := md.ExtractLinks(files, testLogger)
+	results := make([]CheckResult, 0, len(links))
+	for _, l := range links {
+		results = append(results, CheckResult{link: &l, ok: true})
+	}
+
+	client := http.Client{Transport: &mockRoundTripper{statusCodes: map[string]int{"https://example.com/page": 200}}}
+	cfg := url_validator.LinksValidatorConfig{AllowedStatuses: url_validator.PrepareAllowedStatuses(200)}
+
+	fixed, err := fixLinks(results, client, cfg, files, false, io.Discard, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || fixed != 1 {
+		t.Errorf("expected 1 fixed definition for 2 links, got %d for %d", fixed, len(links))
+	}
+	if content, _ := os.ReadFile(file); string(content) != strings.Replace(original, "http://", "https://", 1) {
+		t.Errorf("unexpected content:\n%s", content)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	results := []CheckResult{
+		{link: &md.Link{File: "b.md"}, ok: true},
+		{link: &md.Link{File: "b.md"}, ok: false},
+		{link: &md.Link{File: "a.md"}, skipped: true},
+		{link: &md.Link{File: "a.md"}, ok: true},
+	}
+
+	var sb strings.Builder
+	printSummary(&sb, results)
+
+	want := "Summary:\n" +
+		"  a.md: checked 1, ok 1, broken 0, skipped 1\n" +
+		"  b.md: checked 2, ok 1, broken 1, skipped 0\n" +
+		"Total: checked 3, ok 2, broken 1, skipped 1\n"
+	if sb.String() != want {
+		t.Errorf("unexpected summary:\n%s\nwant:\n%s", sb.String(), want)
+	}
+	if !hasBrokenLinks(results) {
+		t.Errorf("expected broken links to be detected")
+	}
+}
@@ -3,12 +3,15 @@ module github.com/gabkaclassic/marktuator
 go 1.23.3
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/stretchr/testify v1.10.0
 	github.com/yuin/goldmark v1.7.13
+	golang.org/x/net v0.38.0
+	golang.org/x/text v0.23.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
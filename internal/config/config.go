@@ -3,66 +3,279 @@ package config
 import (
 	"flag"
 	"log/slog"
+	"net/http"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gabkaclassic/marktuator/pkg/logger"
+	"github.com/gabkaclassic/marktuator/pkg/md"
 	"github.com/gabkaclassic/marktuator/pkg/url_validator"
 )
 
+// exitUsageError is the process exit code for invalid arguments, the same
+// one the flag package uses.
+const exitUsageError = 2
+
+// CommandFix is the subcommand that rewrites moved links in place.
+const CommandFix = "fix"
+
 type AppConfig struct {
-	Validator  url_validator.LinksValidatorConfig
-	Logger     logger.LoggerConfig
-	TargetPath string
+	Validator     url_validator.LinksValidatorConfig
+	Logger        logger.LoggerConfig
+	Walker        md.WalkerConfig
+	RelativeLinks md.RelativeLinksConfig
+	TargetPath    string
+	// Fix is set by the fix subcommand, DryRun makes it print a diff
+	// instead of writing the files.
+	Fix    bool
+	DryRun bool
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
+// lineList is a repeatable flag whose values may contain commas, so its
+// environment variable takes one value per line.
+type lineList struct{ stringList }
+
 func ParseConfig() AppConfig {
 	var cfg AppConfig
 
 	timeout := flag.Int("timeout", 3, "Timeout in seconds for HTTP requests")
-	statuses := flag.String("status", "200", "Comma-separated list of allowed HTTP status codes")
+	statuses := flag.String("status", "200", "Comma-separated list of allowed HTTP status codes, ranges (200-299), classes (2xx) and negations (!204)")
+	concurrency := flag.Int("concurrency", url_validator.DefaultConcurrency, "Number of links checked concurrently")
+	hostConcurrency := flag.Int("host-concurrency", 4, "Maximum simultaneous requests per host (0 for no limit)")
+	hostRate := flag.Float64("host-rps", 0, "Maximum requests per second per host (0 for no limit)")
+	method := flag.String("method", url_validator.MethodAuto, "HTTP method for checks: HEAD, GET (default: HEAD with GET fallback)")
+	maxBody := flag.Int64("max-body", url_validator.DefaultMaxBodyBytes, "Maximum number of response body bytes read from GET responses")
+	redirects := flag.String("redirects", url_validator.RedirectsFollow, "Redirect policy: follow, no-follow (redirect statuses must then be allowed by -status)")
+	maxRedirects := flag.Int("max-redirects", url_validator.DefaultMaxRedirects, "Maximum number of redirects followed")
+	checkFragments := flag.Bool("external-fragments", true, "Verify fragments of external links against the anchors of the HTML page")
+	maxPage := flag.Int64("max-page-body", url_validator.DefaultMaxPageBytes, "Maximum number of bytes of an HTML page read to find anchors")
+	retries := flag.Int("retries", 2, "Number of retries after a network error or a 429/5xx response")
+	retryDelay := flag.Duration("retry-delay", url_validator.DefaultRetryDelay, "Initial delay between retries, doubled on every attempt")
+	retryMaxDelay := flag.Duration("retry-max-delay", url_validator.DefaultRetryMaxDelay, "Maximum delay between retries, including Retry-After")
+
+	var rules lineList
+	flag.Var(&rules, "rule", "URL rule \"PATTERN;option;...\" with options skip, status=, timeout=, method=, header= (repeatable, first match wins; escape ; as \\;)")
+	var headers lineList
+	flag.Var(&headers, "header", "Header \"Name: value\" sent with every request (repeatable)")
 
 	logFile := flag.String("log", "", "Path to log file (default: stdout)")
 	logLevel := flag.String("level", "info", "Log level (debug, info, warn, error)")
 	useJSON := flag.Bool("json", false, "Use JSON log format")
 
 	targetPath := flag.String("path", "", "Path to file or directory (required)")
+	extensions := flag.String("ext", strings.Join(md.DefaultExtensions, ","), "Comma-separated list of Markdown file extensions")
+	var include, exclude stringList
+	flag.Var(&include, "include", "Glob of files to scan, relative to -path (repeatable, supports **)")
+	flag.Var(&exclude, "exclude", "Glob of files or directories to skip, relative to -path (repeatable, supports **)")
+	anchors := flag.String("anchors", md.DefaultAnchorStrategy, "Heading anchor style: "+strings.Join(md.AnchorStrategyNames(), ", "))
+	indexFiles := flag.String("index-files", strings.Join(md.DefaultIndexFiles, ","), "Comma-separated list of index files directory links resolve to")
+	requireIndex := flag.Bool("require-index", false, "Fail directory links when the directory has no index file")
+	strictCase := flag.Bool("strict-case", false, "Fail relative links whose case differs from the file on disk")
+	root := flag.String("root", "", "Directory links starting with / resolve against (default: -path, or its directory for a file)")
+	baseURL := flag.String("base-url", "", "URL the documentation is published at; links under it are checked against local files")
+	noIgnore := flag.Bool("no-ignore", false, "Do not honour .gitignore and .marktuatorignore files")
+
+	dryRun := flag.Bool("dry-run", false, "With the fix command, print the changes as a diff instead of writing files")
+	configFile := flag.String("config", "", "Path to a YAML or TOML config file (default: .marktuator.yaml or .marktuator.toml found from -path upwards)")
 
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == CommandFix {
+		cfg.Fix = true
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+	if flag.CommandLine.NArg() > 0 {
+		if flag.Arg(0) == CommandFix {
+			slog.Error("The fix command must come before the flags", "argument", flag.Arg(0))
+		} else {
+			slog.Error("Unexpected argument", "argument", flag.Arg(0))
+		}
+		flag.Usage()
+		os.Exit(exitUsageError)
+	}
+	applyConfigSources(configFile, targetPath)
 
-	cfg.Validator = ParseValidatorConfig(*timeout, *statuses)
+	validatorCfg, err := ParseValidatorConfig(*timeout, *statuses)
+	if err != nil {
+		slog.Error("Invalid status list", "status", *statuses, "error", err)
+		os.Exit(exitUsageError)
+	}
+	cfg.Validator = validatorCfg
+	cfg.Validator.Concurrency = *concurrency
+	cfg.Validator.MaxConcurrentPerHost = *hostConcurrency
+	cfg.Validator.RequestsPerSecondPerHost = *hostRate
+	cfg.Validator.Method = strings.ToUpper(*method)
+	if cfg.Validator.Method != url_validator.MethodAuto && cfg.Validator.Method != http.MethodHead && cfg.Validator.Method != http.MethodGet {
+		slog.Error("Invalid HTTP method", "method", *method)
+		os.Exit(exitUsageError)
+	}
+	cfg.Validator.MaxBodyBytes = *maxBody
+	cfg.Validator.Redirects = strings.ToLower(*redirects)
+	if cfg.Validator.Redirects != url_validator.RedirectsFollow && cfg.Validator.Redirects != url_validator.RedirectsNoFollow {
+		slog.Error("Invalid redirect policy", "redirects", *redirects)
+		os.Exit(exitUsageError)
+	}
+	cfg.Validator.MaxRedirects = *maxRedirects
+	cfg.Validator.CheckFragments = *checkFragments
+	cfg.Validator.MaxPageBytes = *maxPage
+	cfg.Validator.Retries = *retries
+	cfg.Validator.RetryDelay = *retryDelay
+	cfg.Validator.RetryMaxDelay = *retryMaxDelay
+	urlRules, err := url_validator.ParseURLRules(rules.stringList...)
+	if err != nil {
+		slog.Error("Invalid URL rule", "error", err)
+		os.Exit(exitUsageError)
+	}
+	cfg.Validator.Rules = urlRules
+	requestHeaders, err := url_validator.ParseHeaders(headers.stringList...)
+	if err != nil {
+		slog.Error("Invalid header", "error", err)
+		os.Exit(exitUsageError)
+	}
+	cfg.Validator.Headers = requestHeaders
 
 	cfg.Logger = ParseLoggerConfig(*logFile, *logLevel, *useJSON)
 
+	cfg.Walker = ParseWalkerConfig(*extensions, include, exclude)
+	cfg.Walker.NoIgnore = *noIgnore
+
+	cfg.RelativeLinks = ParseRelativeLinksConfig(*anchors)
+	cfg.RelativeLinks.IndexFiles = splitList(*indexFiles)
+	cfg.RelativeLinks.RequireIndex = *requireIndex
+	cfg.RelativeLinks.StrictCase = *strictCase
+
 	if *targetPath == "" {
 		slog.Error("Target path is required")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsageError)
+	}
+	if _, err := os.Stat(*targetPath); err != nil {
+		slog.Error("Target path is not accessible", "path", *targetPath, "error", err)
+		os.Exit(exitUsageError)
 	}
 	cfg.TargetPath = *targetPath
+	cfg.RelativeLinks.Root = ResolveRoot(*root, *targetPath)
+	cfg.RelativeLinks.BaseURL = *baseURL
+	cfg.DryRun = *dryRun
 
 	return cfg
 }
 
-func ParseValidatorConfig(timeout int, statusStr string) url_validator.LinksValidatorConfig {
-	statuses := strings.Split(statusStr, ",")
-	allowedStatuses := make([]int, 0, len(statuses))
+// applyConfigSources fills the options not given as flags from MARKTUATOR_*
+// environment variables and then from the config file.
+func applyConfigSources(configFile, targetPath *string) {
+	if err := ApplyValues(flag.CommandLine, EnvValues(flag.CommandLine, os.Environ())); err != nil {
+		slog.Error("Invalid environment variable", "error", err)
+		os.Exit(exitUsageError)
+	}
 
-	for _, s := range statuses {
-		status, err := strconv.Atoi(strings.TrimSpace(s))
-		if err != nil {
-			slog.Error("Invalid status code", "code", s, "error", err)
-			os.Exit(1)
+	path := *configFile
+	if path == "" {
+		start := *targetPath
+		if start == "" {
+			start = "."
+		}
+		var found bool
+		if path, found = FindConfigFile(start); !found {
+			return
 		}
-		allowedStatuses = append(allowedStatuses, status)
+	}
+
+	values, err := LoadConfigFile(path)
+	if err != nil {
+		slog.Error("Invalid config file", "path", path, "error", err)
+		os.Exit(exitUsageError)
+	}
+	if err := ApplyValues(flag.CommandLine, values); err != nil {
+		slog.Error("Invalid config file", "path", path, "error", err)
+		os.Exit(exitUsageError)
+	}
+}
+
+// ResolveRoot returns the site root: root when given, otherwise the target
+// directory or the directory of the target file.
+func ResolveRoot(root, targetPath string) string {
+	if root != "" {
+		return root
+	}
+	if info, err := os.Stat(targetPath); err == nil && !info.IsDir() {
+		return filepath.Dir(targetPath)
+	}
+	return targetPath
+}
+
+func ParseValidatorConfig(timeout int, statusStr string) (url_validator.LinksValidatorConfig, error) {
+	allowedStatuses, err := url_validator.ParseAllowedStatuses(statusStr)
+	if err != nil {
+		return url_validator.LinksValidatorConfig{}, err
 	}
 
 	return url_validator.LinksValidatorConfig{
-		AllowedStatuses: url_validator.PrepareAllowedStatuses(allowedStatuses...),
+		AllowedStatuses: allowedStatuses,
 		Timeout:         time.Duration(timeout) * time.Second,
+	}, nil
+}
+
+func ParseWalkerConfig(extensionsStr string, include, exclude []string) md.WalkerConfig {
+	extensions := splitList(extensionsStr)
+	for i, ext := range extensions {
+		if !strings.HasPrefix(ext, ".") {
+			extensions[i] = "." + ext
+		}
+	}
+
+	includeGlobs, err := md.CompileGlobs(include...)
+	if err != nil {
+		slog.Error("Invalid include pattern", "error", err)
+		os.Exit(exitUsageError)
+	}
+	excludeGlobs, err := md.CompileGlobs(exclude...)
+	if err != nil {
+		slog.Error("Invalid exclude pattern", "error", err)
+		os.Exit(exitUsageError)
+	}
+
+	return md.WalkerConfig{
+		Extensions: extensions,
+		Include:    includeGlobs,
+		Exclude:    excludeGlobs,
+	}
+}
+
+func ParseRelativeLinksConfig(anchors string) md.RelativeLinksConfig {
+	strategy, err := md.GetAnchorStrategy(anchors)
+	if err != nil {
+		slog.Error("Invalid anchor strategy", "error", err)
+		os.Exit(exitUsageError)
+	}
+
+	return md.RelativeLinksConfig{
+		Anchors: strategy,
+	}
+}
+
+func splitList(list string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
 	}
+	return values
 }
 
 func ParseLoggerConfig(logFile, logLevel string, useJSON bool) logger.LoggerConfig {
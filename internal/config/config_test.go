@@ -1,24 +1,59 @@
 package config_test
 
 import (
-	"github.com/gabkaclassic/marktuator/internal/config"
+	"flag"
 	"log/slog"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/gabkaclassic/marktuator/internal/config"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestParseValidatorConfig_Valid(t *testing.T) {
-	validatorCfg := config.ParseValidatorConfig(5, "200, 201, 302")
+	validatorCfg, err := config.ParseValidatorConfig(5, "200, 201, 302")
 
+	assert.NoError(t, err)
 	assert.Equal(t, 5*time.Second, validatorCfg.Timeout)
 	assert.Contains(t, validatorCfg.AllowedStatuses, 200)
 	assert.Contains(t, validatorCfg.AllowedStatuses, 201)
 	assert.Contains(t, validatorCfg.AllowedStatuses, 302)
 }
 
+func TestParseValidatorConfig_Invalid(t *testing.T) {
+	_, err := config.ParseValidatorConfig(5, "200,ok")
+	assert.Error(t, err)
+}
+
+func TestParseWalkerConfig(t *testing.T) {
+	cfg := config.ParseWalkerConfig("md, markdown,.mdx", []string{"docs/**"}, []string{"node_modules", "vendor/**"})
+
+	assert.Equal(t, []string{".md", ".markdown", ".mdx"}, cfg.Extensions)
+	assert.Len(t, cfg.Include, 1)
+	assert.Len(t, cfg.Exclude, 2)
+	assert.True(t, cfg.Exclude[0].Match("web/node_modules"))
+}
+
+func TestParseRelativeLinksConfig(t *testing.T) {
+	cfg := config.ParseRelativeLinksConfig("MkDocs")
+
+	assert.Equal(t, "getting-started", cfg.Anchors.Slug("Getting Started"))
+	assert.Equal(t, "getting-started_1", cfg.Anchors.Duplicate("getting-started", 1))
+}
+
+func TestResolveRoot(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "README.md")
+	assert.NoError(t, os.WriteFile(file, []byte("# Readme"), 0644))
+
+	assert.Equal(t, "site", config.ResolveRoot("site", dir))
+	assert.Equal(t, dir, config.ResolveRoot("", dir))
+	assert.Equal(t, dir, config.ResolveRoot("", file))
+}
+
 func TestParseLoggerConfig(t *testing.T) {
 	cfg := config.ParseLoggerConfig("", "debug", true)
 	assert.False(t, cfg.OutputToFile)
@@ -36,6 +71,7 @@ func TestParseLoggerConfig(t *testing.T) {
 }
 
 func TestParseConfig_Success(t *testing.T) {
+	dir := t.TempDir()
 	os.Args = []string{
 		"cmd",
 		"-timeout=10",
@@ -43,7 +79,7 @@ func TestParseConfig_Success(t *testing.T) {
 		"-log=log.txt",
 		"-level=error",
 		"-json=true",
-		"-path=/some/path",
+		"-path=" + dir,
 	}
 
 	cfg := config.ParseConfig()
@@ -57,7 +93,7 @@ func TestParseConfig_Success(t *testing.T) {
 	assert.Equal(t, slog.LevelError, cfg.Logger.Level)
 	assert.True(t, cfg.Logger.UseJSON)
 
-	assert.Equal(t, "/some/path", cfg.TargetPath)
+	assert.Equal(t, dir, cfg.TargetPath)
 }
 
 func TestHelperMissingPath(t *testing.T) {
@@ -73,3 +109,124 @@ func TestHelperMissingPath(t *testing.T) {
 
 	config.ParseConfig()
 }
+
+func TestHelperNonexistentPath(t *testing.T) {
+	if os.Getenv("TEST_NONEXISTENT_PATH") != "1" {
+		return
+	}
+
+	os.Args = []string{
+		"cmd",
+		"-path=" + filepath.Join(os.TempDir(), "marktuator-nonexistent", "docs"),
+	}
+
+	config.ParseConfig()
+}
+
+func TestHelperUnexpectedArgument(t *testing.T) {
+	if os.Getenv("TEST_UNEXPECTED_ARGUMENT") != "1" {
+		return
+	}
+
+	os.Args = []string{
+		"cmd",
+		"-path=" + os.TempDir(),
+		"fix",
+		"--dry-run",
+	}
+
+	config.ParseConfig()
+}
+
+func TestParseConfig_UsageErrors(t *testing.T) {
+	executable, err := os.Executable()
+	assert.NoError(t, err)
+
+	for _, helper := range []string{"TEST_MISSING_PATH", "TEST_NONEXISTENT_PATH", "TEST_UNEXPECTED_ARGUMENT"} {
+		cmd := exec.Command(executable, "-test.run=^TestHelper")
+		cmd.Env = append(os.Environ(), helper+"=1")
+		err := cmd.Run()
+
+		var exitErr *exec.ExitError
+		if assert.ErrorAs(t, err, &exitErr, helper) {
+			assert.Equal(t, 2, exitErr.ExitCode(), helper)
+		}
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "docs", "guide")
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".marktuator.toml"), []byte("timeout = 5"), 0644))
+
+	path, found := config.FindConfigFile(nested)
+	assert.True(t, found)
+	assert.Equal(t, filepath.Join(dir, ".marktuator.toml"), path)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docs", ".marktuator.yaml"), []byte("timeout: 5"), 0644))
+	path, found = config.FindConfigFile(nested)
+	assert.True(t, found)
+	assert.Equal(t, filepath.Join(dir, "docs", ".marktuator.yaml"), path)
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, ".marktuator.yaml")
+	tomlPath := filepath.Join(dir, ".marktuator.toml")
+	assert.NoError(t, os.WriteFile(yamlPath, []byte("timeout: 5\nstatus: [200, 404]\nexclude:\n  - vendor/**\n  - build\njson: true\n"), 0644))
+	assert.NoError(t, os.WriteFile(tomlPath, []byte("timeout = 5\nstatus = [200, 404]\nexclude = [\"vendor/**\", \"build\"]\njson = true\n"), 0644))
+
+	for _, path := range []string{yamlPath, tomlPath} {
+		values, err := config.LoadConfigFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, config.Values{
+			"timeout": {"5"},
+			"status":  {"200", "404"},
+			"exclude": {"vendor/**", "build"},
+			"json":    {"true"},
+		}, values)
+	}
+
+	assert.NoError(t, os.WriteFile(yamlPath, []byte("path: docs\nroot: ../site\nlog: /var/log/marktuator.log\n"), 0644))
+	values, err := config.LoadConfigFile(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, config.Values{
+		"path": {filepath.Join(dir, "docs")},
+		"root": {filepath.Join(filepath.Dir(dir), "site")},
+		"log":  {"/var/log/marktuator.log"},
+	}, values)
+
+	assert.NoError(t, os.WriteFile(yamlPath, []byte("validator:\n  timeout: 5\n"), 0644))
+	_, err = config.LoadConfigFile(yamlPath)
+	assert.Error(t, err)
+}
+
+func TestApplyValues_Precedence(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	timeout := fs.Int("timeout", 3, "")
+	status := fs.String("status", "200", "")
+	level := fs.String("level", "info", "")
+	useJSON := fs.Bool("json", false, "")
+	assert.NoError(t, fs.Parse([]string{"-timeout=10"}))
+
+	env := config.EnvValues(fs, []string{"MARKTUATOR_LEVEL=debug", "MARKTUATOR_TIMEOUT=20", "OTHER=1"})
+	assert.NoError(t, config.ApplyValues(fs, env))
+	assert.NoError(t, config.ApplyValues(fs, config.Values{
+		"timeout": {"30"},
+		"level":   {"error"},
+		"status":  {"200", "404"},
+		"json":    {"true"},
+	}))
+
+	assert.Equal(t, 10, *timeout)
+	assert.Equal(t, "debug", *level)
+	assert.Equal(t, "200,404", *status)
+	assert.True(t, *useJSON)
+
+	assert.Error(t, config.ApplyValues(fs, config.Values{"unknown": {"1"}}))
+
+	fresh := flag.NewFlagSet("test", flag.ContinueOnError)
+	fresh.Int("timeout", 3, "")
+	assert.Error(t, config.ApplyValues(fresh, config.Values{"timeout": {"soon"}}))
+}
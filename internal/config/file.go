@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables options are read from:
+// -host-concurrency is MARKTUATOR_HOST_CONCURRENCY.
+const EnvPrefix = "MARKTUATOR_"
+
+// ConfigFileNames are the config files looked up from the target directory
+// upwards, in order of preference.
+var ConfigFileNames = []string{".marktuator.yaml", ".marktuator.yml", ".marktuator.toml"}
+
+// pathOptions are the options holding a path, which is relative to the
+// directory of the config file it is set in.
+var pathOptions = []string{"config", "log", "path", "root"}
+
+// Values maps flag names to their values. Repeatable flags may have several.
+type Values map[string][]string
+
+// FindConfigFile returns the first config file in start or its parents.
+// start may be a file, in which case the search begins in its directory.
+func FindConfigFile(start string) (string, bool) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		for _, name := range ConfigFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// LoadConfigFile reads a YAML or TOML config file. Keys are flag names, so
+// "host-concurrency: 2" is the same as -host-concurrency=2; lists are
+// accepted for repeatable and comma-separated options. Relative paths are
+// resolved against the directory of the file.
+func LoadConfigFile(path string) (Values, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(Values, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				values[key] = append(values[key], fmt.Sprint(item))
+			}
+		case map[string]any:
+			return nil, fmt.Errorf("%s: option %q must not be a table", path, key)
+		default:
+			values[key] = []string{fmt.Sprint(v)}
+		}
+	}
+
+	dir := filepath.Dir(path)
+	for _, key := range pathOptions {
+		for i, value := range values[key] {
+			if value != "" && !filepath.IsAbs(value) {
+				values[key][i] = filepath.Join(dir, value)
+			}
+		}
+	}
+	return values, nil
+}
+
+// EnvValues collects the options of fs set in environ, a list of
+// "KEY=value" pairs as returned by os.Environ. Repeatable options take a
+// comma-separated list, or one value per line if values may contain commas.
+func EnvValues(fs *flag.FlagSet, environ []string) Values {
+	env := make(map[string]string, len(environ))
+	for _, pair := range environ {
+		if key, value, found := strings.Cut(pair, "="); found {
+			env[key] = value
+		}
+	}
+
+	values := make(Values)
+	fs.VisitAll(func(f *flag.Flag) {
+		value, found := env[EnvPrefix+strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))]
+		if !found {
+			return
+		}
+		switch f.Value.(type) {
+		case *stringList:
+			values[f.Name] = splitList(value)
+		case *lineList:
+			values[f.Name] = splitLines(value)
+		default:
+			values[f.Name] = []string{value}
+		}
+	})
+	return values
+}
+
+// ApplyValues sets the flags of fs that are not set yet from values.
+// Sources are applied from the highest precedence to the lowest, starting
+// after the command line is parsed. Unknown options are reported as errors.
+func ApplyValues(fs *flag.FlagSet, values Values) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var errs []error
+	for _, name := range sortedKeys(values) {
+		f := fs.Lookup(name)
+		if f == nil {
+			errs = append(errs, fmt.Errorf("unknown option %q", name))
+			continue
+		}
+		if set[name] {
+			continue
+		}
+
+		optionValues := values[name]
+		if !isRepeatable(f) {
+			optionValues = []string{strings.Join(optionValues, ",")}
+		}
+		for _, value := range optionValues {
+			if err := fs.Set(name, value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value %q for option %q: %w", value, name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isRepeatable(f *flag.Flag) bool {
+	switch f.Value.(type) {
+	case *stringList, *lineList:
+		return true
+	}
+	return false
+}
+
+func splitLines(list string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(list, "\n") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func sortedKeys(values Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
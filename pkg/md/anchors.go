@@ -0,0 +1,167 @@
+package md
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// AnchorStrategy generates heading anchors the way a documentation platform
+// renders them.
+type AnchorStrategy interface {
+	// Slug returns the anchor of a heading text.
+	Slug(text string) string
+	// Duplicate returns the anchor of the n-th (starting at 1) repetition
+	// of a heading whose first occurrence got slug.
+	Duplicate(slug string, n int) string
+}
+
+type gitHubAnchors struct{}
+
+func (gitHubAnchors) Slug(text string) string {
+	return generateAnchor(text)
+}
+
+func (gitHubAnchors) Duplicate(slug string, n int) string {
+	return fmt.Sprintf("%s-%d", slug, n)
+}
+
+// gitLabAnchors follows GitLab, which slugs like GitHub but collapses
+// consecutive hyphens.
+type gitLabAnchors struct {
+	gitHubAnchors
+}
+
+var repeatedHyphens = regexp.MustCompile(`-{2,}`)
+
+func (gitLabAnchors) Slug(text string) string {
+	return repeatedHyphens.ReplaceAllString(generateAnchor(text), "-")
+}
+
+// hugoAnchors follows the default "github" heading IDs of Hugo, which keep
+// letters, digits, "_" and "-" only.
+type hugoAnchors struct {
+	gitHubAnchors
+}
+
+func (hugoAnchors) Slug(text string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '_', r == '-':
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+// mkDocsAnchors follows the default slugify of the Python-Markdown toc
+// extension used by MkDocs: the text is reduced to ASCII, runs of spaces and
+// hyphens become a single hyphen and duplicates get "_1", "_2", ...
+type mkDocsAnchors struct{}
+
+var (
+	mkDocsInvalidChars = regexp.MustCompile(`[^\w\s-]`)
+	mkDocsSeparators   = regexp.MustCompile(`[-\s]+`)
+)
+
+func (mkDocsAnchors) Slug(text string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFKD.String(text) {
+		if r <= unicode.MaxASCII {
+			sb.WriteRune(r)
+		}
+	}
+	slug := mkDocsInvalidChars.ReplaceAllString(sb.String(), "")
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	return mkDocsSeparators.ReplaceAllString(slug, "-")
+}
+
+func (mkDocsAnchors) Duplicate(slug string, n int) string {
+	return fmt.Sprintf("%s_%d", slug, n)
+}
+
+var anchorStrategies = map[string]AnchorStrategy{
+	"github": gitHubAnchors{},
+	"gitlab": gitLabAnchors{},
+	"hugo":   hugoAnchors{},
+	"mkdocs": mkDocsAnchors{},
+	// Docusaurus generates heading IDs with github-slugger.
+	"docusaurus": gitHubAnchors{},
+}
+
+const DefaultAnchorStrategy = "github"
+
+// GetAnchorStrategy returns a built-in strategy by name: github, gitlab,
+// hugo, mkdocs or docusaurus.
+func GetAnchorStrategy(name string) (AnchorStrategy, error) {
+	strategy, ok := anchorStrategies[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown anchor strategy %q, expected one of: %s", name, strings.Join(AnchorStrategyNames(), ", "))
+	}
+	return strategy, nil
+}
+
+func AnchorStrategyNames() []string {
+	names := make([]string, 0, len(anchorStrategies))
+	for name := range anchorStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// slugger generates the heading anchors of a single document, resolving
+// repeated headings with the strategy suffixes.
+type slugger struct {
+	strategy    AnchorStrategy
+	occurrences map[string]int
+}
+
+func newSlugger(strategy AnchorStrategy) *slugger {
+	if strategy == nil {
+		strategy = gitHubAnchors{}
+	}
+	return &slugger{strategy: strategy, occurrences: make(map[string]int)}
+}
+
+func (s *slugger) slug(text string) string {
+	base := s.strategy.Slug(text)
+	slug := base
+	for {
+		if _, taken := s.occurrences[slug]; !taken {
+			break
+		}
+		s.occurrences[base]++
+		slug = s.strategy.Duplicate(base, s.occurrences[base])
+	}
+	s.occurrences[slug] = 0
+	return slug
+}
+
+// generateAnchor implements the GitHub heading slug: the text is lowercased,
+// everything but letters, marks, digits, connector punctuation ("_"),
+// hyphens and spaces is dropped and every space becomes a hyphen.
+func generateAnchor(text string) string {
+	text = strings.ToLower(text)
+	text = strings.TrimSpace(text)
+
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r), unicode.IsMark(r), unicode.Is(unicode.Nd, r), unicode.Is(unicode.Pc, r), r == '-':
+			sb.WriteRune(r)
+		case r == ' ', r == '\t':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
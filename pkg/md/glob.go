@@ -0,0 +1,100 @@
+package md
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Glob is a path pattern in the usual shell syntax ("*", "?", "[...]")
+// extended with "**", which matches any number of directories. Patterns
+// without a "/" match at any depth, so "node_modules" excludes every
+// node_modules directory.
+type Glob struct {
+	pattern string
+	regex   *regexp.Regexp
+}
+
+func CompileGlob(pattern string) (Glob, error) {
+	normalized := strings.TrimPrefix(pattern, "./")
+	if !strings.Contains(strings.TrimSuffix(normalized, "/"), "/") {
+		normalized = "**/" + normalized
+	}
+	normalized = strings.TrimPrefix(normalized, "/")
+	normalized = strings.TrimSuffix(normalized, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(normalized); i++ {
+		c := normalized[i]
+		switch {
+		case strings.HasPrefix(normalized[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(normalized[i:], "/**") && i+3 == len(normalized):
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(normalized[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(normalized[i+1:], ']')
+			if end < 0 {
+				return Glob{}, fmt.Errorf("invalid glob %q: unterminated character class", pattern)
+			}
+			class := normalized[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(normalized):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(normalized[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	regex, err := regexp.Compile(sb.String())
+	if err != nil {
+		return Glob{}, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return Glob{pattern: pattern, regex: regex}, nil
+}
+
+func CompileGlobs(patterns ...string) ([]Glob, error) {
+	globs := make([]Glob, 0, len(patterns))
+	for _, pattern := range patterns {
+		glob, err := CompileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+// Match reports whether a slash-separated path relative to the scanned
+// root matches the pattern.
+func (glob Glob) Match(path string) bool {
+	return glob.regex != nil && glob.regex.MatchString(path)
+}
+
+func (glob Glob) String() string {
+	return glob.pattern
+}
+
+func matchAny(globs []Glob, path string) bool {
+	for _, glob := range globs {
+		if glob.Match(path) {
+			return true
+		}
+	}
+	return false
+}
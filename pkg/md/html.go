@@ -0,0 +1,164 @@
+package md
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+)
+
+type htmlLink struct {
+	text   string
+	url    string
+	kind   LinkKind
+	offset int
+}
+
+// htmlLinkAttributes lists the attributes holding link targets for each
+// supported tag.
+var htmlLinkAttributes = map[string][]string{
+	"a":      {"href"},
+	"img":    {"src", "srcset"},
+	"source": {"src", "srcset"},
+}
+
+// htmlSegments returns the source segments of an HTMLBlock or RawHTML node.
+func htmlSegments(n ast.Node) []text.Segment {
+	var segments []text.Segment
+	switch node := n.(type) {
+	case *ast.HTMLBlock:
+		for i := 0; i < node.Lines().Len(); i++ {
+			segments = append(segments, node.Lines().At(i))
+		}
+		if node.HasClosure() {
+			segments = append(segments, node.ClosureLine)
+		}
+	case *ast.RawHTML:
+		for i := 0; i < node.Segments.Len(); i++ {
+			segments = append(segments, node.Segments.At(i))
+		}
+	}
+	return segments
+}
+
+// extractHTMLAnchors returns the fragment targets declared by the raw HTML
+// of a node: id attributes of any element and names of <a> elements.
+func extractHTMLAnchors(n ast.Node, content []byte) []string {
+	var raw bytes.Buffer
+	for _, segment := range htmlSegments(n) {
+		raw.Write(segment.Value(content))
+	}
+
+	anchors := make([]string, 0)
+	tokenizer := html.NewTokenizer(&raw)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return anchors
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		for _, attr := range token.Attr {
+			if attr.Key == "id" || (attr.Key == "name" && token.Data == "a") {
+				anchors = append(anchors, attr.Val)
+			}
+		}
+	}
+}
+
+// extractHTMLLinks tokenizes the raw HTML of an HTMLBlock or RawHTML node
+// and returns the link targets of its tags.
+func extractHTMLLinks(n ast.Node, content []byte) []htmlLink {
+	segments := htmlSegments(n)
+
+	var raw bytes.Buffer
+	starts := make([]int, len(segments))
+	for i, segment := range segments {
+		starts[i] = raw.Len()
+		raw.Write(segment.Value(content))
+	}
+
+	// sourceOffset maps an offset inside raw back to the source document.
+	sourceOffset := func(offset int) int {
+		for i := len(segments) - 1; i >= 0; i-- {
+			if offset >= starts[i] {
+				return segments[i].Start + offset - starts[i]
+			}
+		}
+		return 0
+	}
+
+	links := make([]htmlLink, 0)
+	tokenizer := html.NewTokenizer(bytes.NewReader(raw.Bytes()))
+	position := 0
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return links
+			}
+			break
+		}
+		tagStart := position
+		position += len(tokenizer.Raw())
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		attributes, ok := htmlLinkAttributes[token.Data]
+		if !ok {
+			continue
+		}
+
+		kind := LinkKindImage
+		if token.Data == "a" {
+			kind = LinkKindLink
+		}
+
+		linkText := ""
+		for _, attr := range token.Attr {
+			if attr.Key == "alt" || (kind == LinkKindLink && attr.Key == "title") {
+				linkText = attr.Val
+			}
+		}
+
+		for _, attr := range token.Attr {
+			if !slices.Contains(attributes, attr.Key) {
+				continue
+			}
+			urls := []string{strings.TrimSpace(attr.Val)}
+			if attr.Key == "srcset" {
+				urls = parseSrcset(attr.Val)
+			}
+			for _, url := range urls {
+				links = append(links, htmlLink{
+					text:   linkText,
+					url:    url,
+					kind:   kind,
+					offset: sourceOffset(tagStart),
+				})
+			}
+		}
+	}
+	return links
+}
+
+// parseSrcset returns the URLs of the image candidates in a srcset value.
+func parseSrcset(srcset string) []string {
+	urls := make([]string, 0)
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
@@ -0,0 +1,150 @@
+package md
+
+import (
+	"bufio"
+	"bytes"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	GitIgnoreFile        = ".gitignore"
+	MarktuatorIgnoreFile = ".marktuatorignore"
+)
+
+// ignoreRule is a single pattern of a .gitignore or .marktuatorignore file.
+// The pattern is matched against paths relative to base, the directory
+// holding the ignore file.
+type ignoreRule struct {
+	glob    Glob
+	base    string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules holds the rules of every ignore file seen so far during a walk,
+// outer directories first, so the last matching rule wins like in git.
+type ignoreRules []ignoreRule
+
+func (rules ignoreRules) ignored(relative string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := relative
+		if rule.base != "." {
+			if !strings.HasPrefix(relative, rule.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(relative, rule.base+"/")
+		}
+		if rule.glob.Match(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// load appends the rules of the ignore files found in dir, whose path
+// relative to the walked root is base.
+func (rules ignoreRules) load(dir, base string, log *slog.Logger) ignoreRules {
+	for _, name := range []string{GitIgnoreFile, MarktuatorIgnoreFile} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		log.Debug("Load ignore file", slog.String("path", filepath.Join(dir, name)))
+		patterns, _ := parseIgnoreFile(content)
+		for _, pattern := range patterns {
+			rule, ok := parseIgnoreRule(pattern, base)
+			if !ok {
+				log.Debug("Skip invalid ignore pattern", slog.String("pattern", pattern), slog.String("path", filepath.Join(dir, name)))
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseIgnoreRule(pattern, base string) (ignoreRule, bool) {
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+	}
+	if pattern == "" || pattern == "/" {
+		return rule, false
+	}
+
+	glob, err := CompileGlob(pattern)
+	if err != nil {
+		return rule, false
+	}
+	rule.glob = glob
+	return rule, true
+}
+
+// parseIgnoreFile splits an ignore file into path patterns and URL
+// patterns. Lines holding "://" or starting with "mailto:" are URL patterns,
+// everything else follows the .gitignore syntax.
+func parseIgnoreFile(content []byte) ([]string, []string) {
+	paths := make([]string, 0)
+	urlPatterns := make([]string, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(strings.TrimSuffix(line, `\ `), " ") + " "
+		} else {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.Contains(line, "://") || strings.HasPrefix(line, "mailto:") {
+			urlPatterns = append(urlPatterns, strings.TrimSpace(line))
+		} else {
+			paths = append(paths, line)
+		}
+	}
+	return paths, urlPatterns
+}
+
+// ReadIgnoredURLs returns the URL patterns listed in the .marktuatorignore
+// file of root (or of the directory containing root, when root is a file).
+func ReadIgnoredURLs(root string, log *slog.Logger) []string {
+	dir := root
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		dir = filepath.Dir(root)
+	}
+
+	ignorePath := filepath.Join(dir, MarktuatorIgnoreFile)
+	content, err := os.ReadFile(ignorePath)
+	if err != nil {
+		return nil
+	}
+
+	_, urlPatterns := parseIgnoreFile(content)
+	log.Debug("Read ignored URL patterns", slog.String("path", ignorePath), slog.Int("count", len(urlPatterns)))
+	return urlPatterns
+}
+
+func relativeSlashPath(root, p string) string {
+	relative, err := filepath.Rel(root, p)
+	if err != nil {
+		relative = p
+	}
+	return path.Clean(filepath.ToSlash(relative))
+}
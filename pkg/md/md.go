@@ -1,72 +1,132 @@
 package md
 
 import (
+	"bytes"
 	"fmt"
+	"html"
 	"io/fs"
 	"log/slog"
 	urls "net/url"
 	"os"
+	pathpkg "path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/text"
 )
 
+type LinkKind int
+
+const (
+	LinkKindLink LinkKind = iota
+	LinkKindImage
+)
+
+func (kind LinkKind) String() string {
+	switch kind {
+	case LinkKindImage:
+		return "image"
+	default:
+		return "link"
+	}
+}
+
 type Link struct {
 	File       string
 	Text       string
 	URL        string
 	IsRelative bool
 	Fragment   string
+	Kind       LinkKind
+	Line       int
+	Column     int
+	// Reference is the normalized label of a reference-style link, and
+	// DefinitionLine the line of its "[label]: url" definition.
+	Reference      string
+	DefinitionLine int
+	// URLStart and URLEnd are the byte offsets of the destination in the
+	// file. Both are zero when it could not be located, for example when an
+	// HTML attribute spells it with entities.
+	URLStart int
+	URLEnd   int
+}
+
+// Location returns the link position in the "file:line:column" form
+// understood by editors and CI annotations.
+func (link Link) Location() string {
+	return fmt.Sprintf("%s:%d:%d", link.File, link.Line, link.Column)
 }
 
 func (link Link) String() string {
-	return fmt.Sprintf("[%s](%s%s) in file %s (relative: %t)", link.Text, link.URL, link.Fragment, link.File, link.IsRelative)
+	prefix := ""
+	if link.Kind == LinkKindImage {
+		prefix = "!"
+	}
+	description := fmt.Sprintf("%s[%s](%s) in file %s (relative: %t)", prefix, link.Text, link.URL, link.Location(), link.IsRelative)
+	if link.Reference != "" {
+		description += fmt.Sprintf(" (reference [%s] defined at line %d)", link.Reference, link.DefinitionLine)
+	}
+	return description
 }
 
 func ExtractLinks(files map[string][]byte, log *slog.Logger) []Link {
-	md := goldmark.New()
 	links := make([]Link, 0)
 	log.Debug("Start parsing files")
 	for file, content := range files {
 		log.Debug("Creating new parser for file", slog.String("path", file))
 
-		doc := md.Parser().Parse(text.NewReader(content))
+		doc, definitions := parseDocument(content)
 
 		ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
-			if entering {
-				if link, ok := n.(*ast.Link); ok {
-					var url = string(link.Destination)
-					log.Debug("Extract text from link", slog.String("path", file), slog.String("url", url))
-					var text = extractText(link, content)
-
-					parsedUrl, err := urls.Parse(url)
+			if !entering {
+				return ast.WalkContinue, nil
+			}
 
-					if err != nil {
-						slog.Debug("Invalid URL found, skip", slog.String("error", err.Error()), slog.String("url", url))
-						return ast.WalkContinue, nil
+			var url string
+			var kind LinkKind
+			switch node := n.(type) {
+			case *ast.Link:
+				url = string(node.Destination)
+				kind = LinkKindLink
+			case *ast.Image:
+				url = string(node.Destination)
+				kind = LinkKindImage
+			case *ast.HTMLBlock, *ast.RawHTML:
+				for _, htmlLink := range extractHTMLLinks(n, content) {
+					if newLink, ok := makeLink(file, htmlLink.text, htmlLink.url, htmlLink.kind, htmlLink.offset, content, log); ok {
+						newLink.URLStart, newLink.URLEnd = attributeSpan(content, htmlLink.offset, htmlLink.url)
+						links = append(links, newLink)
 					}
+				}
+				return ast.WalkContinue, nil
+			default:
+				return ast.WalkContinue, nil
+			}
 
-					isRelative := !parsedUrl.IsAbs() && !strings.HasPrefix(url, "mailto:") && url != ""
-
-					fragment := parsedUrl.Fragment
+			log.Debug("Extract text from link", slog.String("path", file), slog.String("url", url))
+			var text = extractText(n, content)
 
-					newLink := Link{
-						File:       file,
-						Text:       text,
-						URL:        url,
-						IsRelative: isRelative,
-						Fragment:   fragment,
-					}
-					links = append(
-						links,
-						newLink,
-					)
-					log.Debug("Found new link in file", slog.Any("link", newLink))
+			newLink, ok := makeLink(file, text, url, kind, linkOffset(n, content), content, log)
+			if !ok {
+				return ast.WalkContinue, nil
+			}
+			if label, ok := referenceLabel(n, content); ok {
+				if def, defined := definitions[label]; defined {
+					newLink.Reference = label
+					newLink.DefinitionLine, _ = offsetToPosition(content, def.offset)
+					newLink.URLStart, newLink.URLEnd = destinationSpan(content, def.offset, "]:", url)
 				}
+			} else {
+				newLink.URLStart, newLink.URLEnd = destinationSpan(content, textEnd(n, content), "](", url)
 			}
+			links = append(
+				links,
+				newLink,
+			)
 			return ast.WalkContinue, nil
 		})
 	}
@@ -75,6 +135,34 @@ func ExtractLinks(files map[string][]byte, log *slog.Logger) []Link {
 	return links
 }
 
+func makeLink(file, text, url string, kind LinkKind, offset int, content []byte, log *slog.Logger) (Link, bool) {
+	parsedUrl, err := urls.Parse(url)
+
+	if err != nil {
+		log.Debug("Invalid URL found, skip", slog.String("error", err.Error()), slog.String("url", url))
+		return Link{}, false
+	}
+
+	// Protocol-relative URLs (//host/path) point to another host.
+	isRelative := !parsedUrl.IsAbs() && parsedUrl.Host == "" && !strings.HasPrefix(url, "mailto:") && url != ""
+
+	fragment := parsedUrl.Fragment
+	line, column := offsetToPosition(content, offset)
+
+	newLink := Link{
+		File:       file,
+		Text:       text,
+		URL:        url,
+		IsRelative: isRelative,
+		Fragment:   fragment,
+		Kind:       kind,
+		Line:       line,
+		Column:     column,
+	}
+	log.Debug("Found new link in file", slog.Any("link", newLink))
+	return newLink, true
+}
+
 func extractText(node ast.Node, content []byte) string {
 	var sb strings.Builder
 
@@ -94,15 +182,241 @@ func extractText(node ast.Node, content []byte) string {
 	return sb.String()
 }
 
-func ReadMdFiles(path string, log *slog.Logger) map[string][]byte {
+// destinationSpan returns the byte span of url written as the destination
+// following opener, "](" for inline links or "]:" for definitions, at or
+// after from.
+func destinationSpan(content []byte, from int, opener string, url string) (int, int) {
+	i := bytes.Index(content[from:], []byte(opener))
+	if i < 0 {
+		return 0, 0
+	}
+	start := from + i + len(opener)
+	for start < len(content) && strings.ContainsRune(" \t\r\n", rune(content[start])) {
+		start++
+	}
+	if start < len(content) && content[start] == '<' {
+		start++
+	}
+	if !bytes.HasPrefix(content[start:], []byte(url)) {
+		return 0, 0
+	}
+	return start, start + len(url)
+}
+
+// attributeSpan returns the byte span of url inside the HTML tag starting
+// at tagStart.
+func attributeSpan(content []byte, tagStart int, url string) (int, int) {
+	tagEnd := bytes.IndexByte(content[tagStart:], '>')
+	if tagEnd < 0 || url == "" {
+		return 0, 0
+	}
+	i := bytes.Index(content[tagStart:tagStart+tagEnd], []byte(url))
+	if i < 0 {
+		return 0, 0
+	}
+	return tagStart + i, tagStart + i + len(url)
+}
+
+// textEnd returns the offset of the "]" closing the text of link node n,
+// past any image or link nested in the text.
+func textEnd(n ast.Node, content []byte) int {
+	open := linkOffset(n, content)
+	if open < len(content) && content[open] == '!' {
+		open++
+	}
+	if end := closingBracket(content, open); end >= 0 {
+		return end
+	}
+	return min(open+1, len(content))
+}
+
+// linkOffset returns the byte offset of the opening "[" (or "![" for
+// images) of a link node. Goldmark keeps no positions for inline nodes, so
+// the offset is derived from the segments of the surrounding text.
+func linkOffset(n ast.Node, content []byte) int {
+	offset := -1
+	if bound, ok := textStart(n, content); ok {
+		offset = bytes.LastIndexByte(content[:bound], '[')
+	} else {
+		lowerBound := precedingOffset(n)
+		if i := bytes.IndexByte(content[lowerBound:], '['); i >= 0 {
+			offset = lowerBound + i
+		}
+	}
+
+	if offset < 0 {
+		return precedingOffset(n)
+	}
+	if _, isImage := n.(*ast.Image); isImage && offset > 0 && content[offset-1] == '!' {
+		offset--
+	}
+	return offset
+}
+
+// textStart returns the offset of the first text of link node n. When the
+// text starts with a nested image or link, like a badge, it is the opener of
+// that node, so the search for the "[" of n skips the nested one.
+func textStart(n ast.Node, content []byte) (int, bool) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch c := child.(type) {
+		case *ast.Image, *ast.Link:
+			return linkOffset(c, content), true
+		case *ast.Text:
+			return c.Segment.Start, true
+		case *ast.RawHTML:
+			if c.Segments.Len() > 0 {
+				return c.Segments.At(0).Start, true
+			}
+		default:
+			if start, ok := textStart(c, content); ok {
+				return start, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// precedingOffset returns the offset where the text preceding n ends, or the
+// start of the enclosing block when n is its first inline.
+func precedingOffset(n ast.Node) int {
+	for node := n; node != nil; node = node.Parent() {
+		if node.Type() == ast.TypeBlock {
+			if node.Lines().Len() > 0 {
+				return node.Lines().At(0).Start
+			}
+			return 0
+		}
+		for prev := node.PreviousSibling(); prev != nil; prev = prev.PreviousSibling() {
+			if segment, ok := lastSegment(prev); ok {
+				return segment.Stop
+			}
+		}
+	}
+	return 0
+}
+
+func firstSegment(n ast.Node) (text.Segment, bool) {
+	switch node := n.(type) {
+	case *ast.Text:
+		return node.Segment, true
+	case *ast.RawHTML:
+		if node.Segments.Len() > 0 {
+			return node.Segments.At(0), true
+		}
+	}
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if segment, ok := firstSegment(child); ok {
+			return segment, true
+		}
+	}
+	return text.Segment{}, false
+}
+
+func lastSegment(n ast.Node) (text.Segment, bool) {
+	switch node := n.(type) {
+	case *ast.Text:
+		return node.Segment, true
+	case *ast.RawHTML:
+		if node.Segments.Len() > 0 {
+			return node.Segments.At(node.Segments.Len() - 1), true
+		}
+	}
+	for child := n.LastChild(); child != nil; child = child.PreviousSibling() {
+		if segment, ok := lastSegment(child); ok {
+			return segment, true
+		}
+	}
+	return text.Segment{}, false
+}
+
+// offsetToPosition converts a byte offset into a 1-based line and a 1-based
+// column counted in characters.
+func offsetToPosition(content []byte, offset int) (int, int) {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	lineStart := bytes.LastIndexByte(content[:offset], '\n') + 1
+	line := bytes.Count(content[:lineStart], []byte{'\n'}) + 1
+	column := utf8.RuneCount(content[lineStart:offset]) + 1
+	return line, column
+}
+
+var DefaultExtensions = []string{".md", ".markdown", ".mdx"}
+
+type WalkerConfig struct {
+	// Extensions of the files to read, DefaultExtensions when empty.
+	Extensions []string
+	// Include, when not empty, limits reading to the files matching any of
+	// the patterns. Exclude skips matching files and whole directories.
+	Include []Glob
+	Exclude []Glob
+	// NoIgnore disables .gitignore and .marktuatorignore handling.
+	NoIgnore bool
+}
+
+func (cfg WalkerConfig) hasExtension(path string) bool {
+	extensions := cfg.Extensions
+	if len(extensions) == 0 {
+		extensions = DefaultExtensions
+	}
+	ext := filepath.Ext(path)
+	for _, extension := range extensions {
+		if strings.EqualFold(ext, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadMdFiles reads the Markdown files under root, or root itself when it
+// is a file. Unreadable entries below root are logged and skipped; an error
+// is returned only when root cannot be read.
+func ReadMdFiles(root string, cfg WalkerConfig, log *slog.Logger) (map[string][]byte, error) {
 
 	filesContent := make(map[string][]byte)
+	var ignores ignoreRules
+	var rootErr error
+
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Error("Path reading error", slog.String("path", path), slog.String("error", err.Error()))
+			if path == root {
+				rootErr = err
+				return err
+			}
+			return nil
+		}
+
+		relative := relativeSlashPath(root, path)
+		isRoot := relative == "."
+
+		if !isRoot && matchAny(cfg.Exclude, relative) {
+			log.Debug("Skip excluded path", slog.String("path", path))
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isRoot && !cfg.NoIgnore && (d.Name() == ".git" || ignores.ignored(relative, d.IsDir())) {
+			log.Debug("Skip ignored path", slog.String("path", path))
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
-	filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
 		if d.IsDir() {
 			log.Debug("Reading directory", slog.String("path", path))
+			if !cfg.NoIgnore {
+				ignores = ignores.load(path, relative, log)
+			}
 			return nil
 		} else {
+			if !isRoot && (!cfg.hasExtension(path) || len(cfg.Include) > 0 && !matchAny(cfg.Include, relative)) {
+				log.Debug("Skip non-markdown or not included file", slog.String("path", path))
+				return nil
+			}
 
 			content, err := os.ReadFile(path)
 
@@ -116,76 +430,269 @@ func ReadMdFiles(path string, log *slog.Logger) map[string][]byte {
 
 		return nil
 	})
-	return filesContent
+	return filesContent, rootErr
+}
+
+var DefaultIndexFiles = []string{"README.md", "index.md", "_index.md"}
+
+type RelativeLinksConfig struct {
+	// Anchors generates the heading anchors fragments are matched against,
+	// GitHub anchors when nil.
+	Anchors AnchorStrategy
+	// IndexFiles are looked up, in order, when a link targets a directory,
+	// DefaultIndexFiles when nil. Unless RequireIndex is set, a directory
+	// without an index file is still a valid target for links without a
+	// fragment.
+	IndexFiles   []string
+	RequireIndex bool
+	// StrictCase fails links whose spelling differs in case from the file
+	// on disk, even on case-insensitive filesystems.
+	StrictCase bool
+	// Root is the directory links starting with "/" are resolved against.
+	// When empty they are resolved like other relative links.
+	Root string
+	// BaseURL is the address the documentation is published at. Links
+	// under it are checked against the files in Root instead of the network.
+	BaseURL string
+}
+
+func (cfg RelativeLinksConfig) indexFiles() []string {
+	if cfg.IndexFiles == nil {
+		return DefaultIndexFiles
+	}
+	return cfg.IndexFiles
 }
 
-func CheckRelativeLink(relativeUrl string, path string, files map[string][]byte, log *slog.Logger) bool {
+func CheckRelativeLink(relativeUrl string, path string, files map[string][]byte, cfg RelativeLinksConfig, log *slog.Logger) bool {
 
-	u, err := urls.Parse(relativeUrl)
+	linkPath, fragment, err := splitRelativeURL(relativeUrl)
 	if err != nil {
 		log.Debug("Invalid relative URL", slog.String("url", relativeUrl), slog.String("error", err.Error()))
 		return false
 	}
 
-	baseDir := filepath.Dir(path)
-	targetPath := filepath.Join(baseDir, u.Path)
+	// Links like "#usage" point into the document they are written in.
+	targetPath := path
+	base := filepath.Dir(path)
+	switch {
+	case strings.HasPrefix(linkPath, "/") && cfg.Root != "":
+		base = cfg.Root
+		targetPath = filepath.Join(cfg.Root, linkPath)
+	case linkPath != "":
+		targetPath = filepath.Join(base, linkPath)
+	}
 
+	// Targets are looked up on disk, so links to scripts, images and other
+	// assets work; loaded Markdown is only needed to resolve fragments.
 	content, exists := files[targetPath]
-
+	isDir := false
 	if !exists {
-		log.Info("File for relative link is not found", slog.String("path", targetPath))
-		return false
+		info, err := os.Stat(targetPath)
+		if err != nil {
+			log.Info("File for relative link is not found", slog.String("path", targetPath))
+			return false
+		}
+		isDir = info.IsDir()
+	}
+	if cfg.StrictCase && linkPath != "" {
+		if actual, mismatch := caseMismatch(base, targetPath); mismatch {
+			log.Info("Relative link differs in case from file on disk", slog.String("path", targetPath), slog.String("actual", actual))
+			return false
+		}
 	}
 
-	if u.Fragment == "" {
+	if isDir {
+		indexPath, found := findIndexFile(targetPath, files, cfg)
+		if !found {
+			if cfg.RequireIndex || fragment != "" {
+				log.Info("Index file for relative link is not found", slog.String("path", targetPath))
+				return false
+			}
+			log.Debug("Relative link points to directory", slog.String("path", targetPath))
+			return true
+		}
+		log.Debug("Relative link resolved to index file", slog.String("path", targetPath), slog.String("index", indexPath))
+		if actual, mismatch := caseMismatch(targetPath, indexPath); cfg.StrictCase && mismatch {
+			log.Info("Index file differs in case from file on disk", slog.String("path", indexPath), slog.String("actual", actual))
+			return false
+		}
+		targetPath = indexPath
+		content, exists = files[indexPath]
+	}
+
+	if fragment == "" {
 		log.Debug("Fragment for relative link not found", slog.Any("link", relativeUrl), slog.String("path", targetPath))
 		return true
 	}
 
-	found := hasMDHeader(u.Fragment, content, log)
+	if !exists {
+		if !(WalkerConfig{}).hasExtension(targetPath) {
+			log.Debug("Fragment of non-Markdown target is not verified", slog.String("path", targetPath), slog.String("fragment", fragment))
+			return true
+		}
+		if content, err = os.ReadFile(targetPath); err != nil {
+			log.Info("File for relative link is not readable", slog.String("path", targetPath), slog.String("error", err.Error()))
+			return false
+		}
+	}
+
+	found := hasMDHeader(fragment, content, cfg.Anchors, log)
 
 	if !found {
-		log.Info("Fragment for relative link is not found", slog.String("fragment", u.Fragment), slog.String("path", path), slog.String("url", relativeUrl))
+		log.Info("Fragment for relative link is not found", slog.String("fragment", fragment), slog.String("path", path), slog.String("url", relativeUrl))
 	}
 
 	return found
 }
 
-func hasMDHeader(fragment string, content []byte, log *slog.Logger) bool {
-	md := goldmark.New()
-	doc := md.Parser().Parse(text.NewReader(content))
+// splitRelativeURL strips the query and fragment of a relative link and
+// percent-decodes both parts. A "%" that does not start an escape is kept
+// as is, like browsers do.
+func splitRelativeURL(relativeUrl string) (string, string, error) {
+	linkPath, fragment, _ := strings.Cut(relativeUrl, "#")
+	linkPath, _, _ = strings.Cut(linkPath, "?")
 
-	found := false
+	// Protocol-relative links like "//cdn.example.com/x.js" are not files.
+	if strings.HasPrefix(linkPath, "//") {
+		return "", "", fmt.Errorf("link has a host: %s", relativeUrl)
+	}
 
-	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
-		if entering {
-			if heading, ok := n.(*ast.Heading); ok {
-				text := extractText(heading, content)
-				anchor := generateAnchor(text)
-
-				if anchor == fragment {
-					found = true
-					return ast.WalkStop, nil
+	return unescape(linkPath), unescape(fragment), nil
+}
+
+func unescape(s string) string {
+	if unescaped, err := urls.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// caseMismatch reports the first entry between base and target whose name
+// on disk differs from the link only in case.
+func caseMismatch(base, target string) (string, bool) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil || rel == "." {
+		return "", false
+	}
+
+	dir := base
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if name != ".." {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return "", false
+			}
+			actual := ""
+			for _, entry := range entries {
+				if entry.Name() == name {
+					actual = ""
+					break
+				}
+				if strings.EqualFold(entry.Name(), name) {
+					actual = entry.Name()
 				}
 			}
+			if actual != "" {
+				return filepath.Join(dir, actual), true
+			}
 		}
-		return ast.WalkContinue, nil
-	})
+		dir = filepath.Join(dir, name)
+	}
+	return "", false
+}
+
+// SiteLink converts an absolute link under cfg.BaseURL into a link relative
+// to cfg.Root. Published pages are matched to their sources, so both
+// "/guide/install.html" and "/guide/install" may map to "/guide/install.md".
+func SiteLink(url string, cfg RelativeLinksConfig) (string, bool) {
+	if cfg.BaseURL == "" || cfg.Root == "" {
+		return "", false
+	}
+	base := strings.TrimSuffix(cfg.BaseURL, "/")
+	if url != base && !strings.HasPrefix(url, base+"/") && !strings.HasPrefix(url, base+"#") && !strings.HasPrefix(url, base+"?") {
+		return "", false
+	}
 
+	u, err := urls.Parse("/" + strings.TrimPrefix(strings.TrimPrefix(url, base), "/"))
+	if err != nil {
+		return "", false
+	}
+
+	candidates := []string{u.Path}
+	switch ext := pathpkg.Ext(u.Path); {
+	case ext == ".html" || ext == ".htm":
+		candidates = append(candidates, strings.TrimSuffix(u.Path, ext)+".md")
+	case ext == "" && !strings.HasSuffix(u.Path, "/"):
+		candidates = append(candidates, u.Path+".md")
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(filepath.Join(cfg.Root, filepath.FromSlash(candidate))); err == nil {
+			u.Path = candidate
+			break
+		}
+	}
+
+	return u.String(), true
+}
+
+func findIndexFile(dir string, files map[string][]byte, cfg RelativeLinksConfig) (string, bool) {
+	for _, index := range cfg.indexFiles() {
+		indexPath := filepath.Join(dir, index)
+		if _, loaded := files[indexPath]; loaded {
+			return indexPath, true
+		}
+		if info, err := os.Stat(indexPath); err == nil && !info.IsDir() {
+			return indexPath, true
+		}
+	}
+	return "", false
+}
+
+func hasMDHeader(fragment string, content []byte, anchors AnchorStrategy, log *slog.Logger) bool {
+	_, found := collectAnchors(content, anchors)[fragment]
 	return found
 }
 
-func generateAnchor(text string) string {
-	text = strings.ToLower(text)
-	text = strings.TrimSpace(text)
-	text = strings.ReplaceAll(text, " ", "-")
-	text = strings.ReplaceAll(text, "\t", "-")
+// collectAnchors returns every fragment target of a document: generated
+// heading anchors, explicit heading IDs ("## Title {#id}"), id and name
+// attributes of raw HTML and footnote anchors.
+func collectAnchors(content []byte, strategy AnchorStrategy) map[string]struct{} {
+	doc, _ := parseDocument(content)
 
-	var sb strings.Builder
-	for _, r := range text {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			sb.WriteRune(r)
+	anchors := make(map[string]struct{})
+	slugs := newSlugger(strategy)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
 		}
-	}
-	return sb.String()
+
+		switch node := n.(type) {
+		case *ast.Heading:
+			text := html.UnescapeString(extractText(node, content))
+			anchors[slugs.slug(text)] = struct{}{}
+
+			if id, ok := node.AttributeString("id"); ok {
+				if value, isBytes := id.([]byte); isBytes {
+					anchors[string(value)] = struct{}{}
+				}
+			}
+		case *ast.HTMLBlock, *ast.RawHTML:
+			for _, anchor := range extractHTMLAnchors(n, content) {
+				anchors[anchor] = struct{}{}
+			}
+		case *east.Footnote:
+			// goldmark and Hugo number footnotes, GitHub and MkDocs use
+			// their labels.
+			for _, id := range []string{strconv.Itoa(node.Index), string(node.Ref)} {
+				anchors["fn:"+id] = struct{}{}
+				anchors["fnref:"+id] = struct{}{}
+				anchors["fn-"+id] = struct{}{}
+				anchors["fnref-"+id] = struct{}{}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return anchors
 }
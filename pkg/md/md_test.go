@@ -1,6 +1,8 @@
 package md
 
 import (
+	"errors"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -171,6 +173,187 @@ func TestExtractLinks_TextExtraction(t *testing.T) {
 	}
 }
 
+func TestExtractLinks_Images(t *testing.T) {
+	content := []byte("![Diagram](img/diagram.png) and [link](doc.md) and ![Logo](https://example.com/logo.svg)")
+	files := map[string][]byte{
+		"images.md": content,
+	}
+
+	links := ExtractLinks(files, testLogger)
+
+	if len(links) != 3 {
+		t.Fatalf("expected 3 links, got %d", len(links))
+	}
+
+	tests := []struct {
+		text       string
+		url        string
+		isRelative bool
+		kind       LinkKind
+	}{
+		{"Diagram", "img/diagram.png", true, LinkKindImage},
+		{"link", "doc.md", true, LinkKindLink},
+		{"Logo", "https://example.com/logo.svg", false, LinkKindImage},
+	}
+
+	for i, want := range tests {
+		got := links[i]
+		if got.Text != want.text || got.URL != want.url || got.IsRelative != want.isRelative || got.Kind != want.kind {
+			t.Errorf("link %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestExtractLinks_Position(t *testing.T) {
+	content := []byte("# Title\n\nSee [first](a.md) and [**second**](b.md).\n\n" +
+		"- item ![](c.png)\n" +
+		"> Привет [third](d.md)\n" +
+		"[![Build](badge.svg)](https://ci.example.com) [after](e.md)\n")
+	files := map[string][]byte{
+		"pos.md": content,
+	}
+
+	links := ExtractLinks(files, testLogger)
+
+	if len(links) != 7 {
+		t.Fatalf("expected 7 links, got %d", len(links))
+	}
+
+	tests := []struct {
+		url    string
+		line   int
+		column int
+	}{
+		{"a.md", 3, 5},
+		{"b.md", 3, 23},
+		{"c.png", 5, 8},
+		{"d.md", 6, 10},
+		{"https://ci.example.com", 7, 1},
+		{"badge.svg", 7, 2},
+		{"e.md", 7, 47},
+	}
+
+	for i, want := range tests {
+		got := links[i]
+		if got.URL != want.url || got.Line != want.line || got.Column != want.column {
+			t.Errorf("link %d: got %s at %d:%d, want %s at %d:%d", i, got.URL, got.Line, got.Column, want.url, want.line, want.column)
+		}
+	}
+
+	if loc := links[0].Location(); loc != "pos.md:3:5" {
+		t.Errorf("unexpected location: %s", loc)
+	}
+}
+
+func TestLink_String(t *testing.T) {
+	link := Link{File: "doc.md", Text: "Usage", URL: "https://example.com/page#usage", Fragment: "usage", Line: 3, Column: 5}
+
+	want := "[Usage](https://example.com/page#usage) in file doc.md:3:5 (relative: false)"
+	if got := link.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractLinks_ReferenceLinks(t *testing.T) {
+	content := []byte("See [full][Docs], [collapsed][] and [inline](c.md). [![Build](badge.svg)][ci]\n\n" +
+		"[docs]: https://example.com/docs\n" +
+		"[collapsed]: b.md\n" +
+		"[ci]: https://ci.example.com\n")
+	files := map[string][]byte{
+		"refs.md": content,
+	}
+
+	links := ExtractLinks(files, testLogger)
+
+	if len(links) != 5 {
+		t.Fatalf("expected 5 links, got %d", len(links))
+	}
+
+	tests := []struct {
+		url            string
+		reference      string
+		definitionLine int
+	}{
+		{"https://example.com/docs", "docs", 3},
+		{"b.md", "collapsed", 4},
+		{"c.md", "", 0},
+		{"https://ci.example.com", "ci", 5},
+		{"badge.svg", "", 0},
+	}
+
+	for i, want := range tests {
+		got := links[i]
+		if got.URL != want.url || got.Reference != want.reference || got.DefinitionLine != want.definitionLine {
+			t.Errorf("link %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestExtractLinks_HTML(t *testing.T) {
+	content := []byte("<p align=\"center\">\n" +
+		"  <img src=\"logo.png\" alt=\"Logo\">\n" +
+		"  <picture><source srcset=\"dark.png 1x, dark@2x.png 2x\"></picture>\n" +
+		"</p>\n\n" +
+		"Inline <a href=\"https://example.com/docs\">docs</a> link.\n")
+	files := map[string][]byte{
+		"html.md": content,
+	}
+
+	links := ExtractLinks(files, testLogger)
+
+	if len(links) != 4 {
+		t.Fatalf("expected 4 links, got %d: %v", len(links), links)
+	}
+
+	tests := []struct {
+		url    string
+		kind   LinkKind
+		line   int
+		column int
+	}{
+		{"logo.png", LinkKindImage, 2, 3},
+		{"dark.png", LinkKindImage, 3, 12},
+		{"dark@2x.png", LinkKindImage, 3, 12},
+		{"https://example.com/docs", LinkKindLink, 6, 8},
+	}
+
+	for i, want := range tests {
+		got := links[i]
+		if got.URL != want.url || got.Kind != want.kind || got.Line != want.line || got.Column != want.column {
+			t.Errorf("link %d: got %+v, want %+v", i, got, want)
+		}
+	}
+
+	if links[0].Text != "Logo" || !links[0].IsRelative || links[3].IsRelative {
+		t.Errorf("unexpected link attributes: %+v, %+v", links[0], links[3])
+	}
+}
+
+func TestFindReferenceIssues(t *testing.T) {
+	content := []byte("Use [guide][] and [missing link][nowhere].\n\n" +
+		"[![Build](badge.svg)][ci]\n\n" +
+		"Code `[x][not-a-ref]` is ignored.\n\n" +
+		"[guide]: guide.md\n" +
+		"[ci]: https://ci.example.com\n" +
+		"[unused]: https://example.com\n")
+	files := map[string][]byte{
+		"issues.md": content,
+	}
+
+	issues := FindReferenceIssues(files, testLogger)
+
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d: %v", len(issues), issues)
+	}
+
+	if issues[0].Kind != ReferenceIssueUndefinedLabel || issues[0].Label != "nowhere" || issues[0].Line != 1 || issues[0].Column != 19 {
+		t.Errorf("unexpected first issue: %+v", issues[0])
+	}
+	if issues[1].Kind != ReferenceIssueUnusedDefinition || issues[1].Label != "unused" || issues[1].Line != 9 || issues[1].Column != 1 {
+		t.Errorf("unexpected second issue: %+v", issues[1])
+	}
+}
+
 func TestGenerateAnchor(t *testing.T) {
 	cases := map[string]string{
 		"Heading One":     "heading-one",
@@ -187,6 +370,93 @@ func TestGenerateAnchor(t *testing.T) {
 	}
 }
 
+func TestGenerateAnchor_GitHubConformance(t *testing.T) {
+	cases := []struct {
+		heading string
+		want    string
+	}{
+		{"Hello World", "hello-world"},
+		{"Привет, мир!", "привет-мир"},
+		{"Установка и настройка", "установка-и-настройка"},
+		{"Café au lait", "café-au-lait"},
+		{"日本語の見出し", "日本語の見出し"},
+		{"snake_case_name", "snake_case_name"},
+		{"Already-hyphenated title", "already-hyphenated-title"},
+		{"Version 2.0 (beta)", "version-20-beta"},
+		{"What's new?", "whats-new"},
+		{"A  double  space", "a--double--space"},
+		{"C++ & Go", "c--go"},
+		{"Emoji 🚀 launch", "emoji--launch"},
+		{"Ünïcödé Ñame", "ünïcödé-ñame"},
+		{"100% done", "100-done"},
+	}
+
+	for _, tt := range cases {
+		if got := generateAnchor(tt.heading); got != tt.want {
+			t.Errorf("generateAnchor(%q) = %q, want %q", tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestAnchorStrategies(t *testing.T) {
+	headings := []string{"Héllo  World!", "Getting_Started -- Fast", "Héllo  World!"}
+
+	cases := map[string][]string{
+		"github":     {"héllo--world", "getting_started----fast", "héllo--world-1"},
+		"docusaurus": {"héllo--world", "getting_started----fast", "héllo--world-1"},
+		"gitlab":     {"héllo-world", "getting_started-fast", "héllo-world-1"},
+		"hugo":       {"héllo--world", "getting_started----fast", "héllo--world-1"},
+		"mkdocs":     {"hello-world", "getting_started-fast", "hello-world_1"},
+	}
+
+	for name, want := range cases {
+		strategy, err := GetAnchorStrategy(name)
+		if err != nil {
+			t.Fatalf("GetAnchorStrategy(%q): %v", name, err)
+		}
+		slugs := newSlugger(strategy)
+		for i, heading := range headings {
+			if got := slugs.slug(heading); got != want[i] {
+				t.Errorf("%s: slug(%q) = %q, want %q", name, heading, got, want[i])
+			}
+		}
+	}
+
+	if _, err := GetAnchorStrategy("unknown"); err == nil {
+		t.Errorf("expected error for unknown strategy")
+	}
+}
+
+func TestHasMDHeader_DuplicatesAndUnicode(t *testing.T) {
+	content := []byte(`
+# Пример
+## Example
+## Example
+## Example
+## Example 1
+## Fish &amp; Chips
+`)
+
+	tests := []struct {
+		fragment string
+		want     bool
+	}{
+		{"пример", true},
+		{"example", true},
+		{"example-1", true},
+		{"example-2", true},
+		{"example-1-1", true},
+		{"example-3", false},
+		{"fish--chips", true},
+	}
+
+	for _, tt := range tests {
+		if got := hasMDHeader(tt.fragment, content, nil, testLogger); got != tt.want {
+			t.Errorf("hasMDHeader(%q) = %t, want %t", tt.fragment, got, tt.want)
+		}
+	}
+}
+
 func TestHasMDHeader(t *testing.T) {
 	content := []byte(`
 # First Header
@@ -205,10 +475,50 @@ func TestHasMDHeader(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		if got := hasMDHeader(tt.fragment, content, testLogger); got != tt.want {
+		if got := hasMDHeader(tt.fragment, content, nil, testLogger); got != tt.want {
+			t.Errorf("hasMDHeader(%q) = %t, want %t", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestHasMDHeader_ExplicitAndHTMLAnchors(t *testing.T) {
+	content := []byte(`
+## Install {#setup}
+
+<a id="legacy-name"></a>
+
+Text with an inline <span id="inline-anchor">anchor</span> and a note[^note].
+
+<a name="old-style"></a>
+
+[^note]: The footnote.
+`)
+
+	tests := []struct {
+		fragment string
+		want     bool
+	}{
+		{"setup", true},
+		{"install", true},
+		{"legacy-name", true},
+		{"inline-anchor", true},
+		{"old-style", true},
+		{"fn:1", true},
+		{"fnref:1", true},
+		{"fn-note", true},
+		{"fnref-note", true},
+		{"missing", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasMDHeader(tt.fragment, content, nil, testLogger); got != tt.want {
 			t.Errorf("hasMDHeader(%q) = %t, want %t", tt.fragment, got, tt.want)
 		}
 	}
+
+	if issues := FindReferenceIssues(map[string][]byte{"notes.md": content}, testLogger); len(issues) != 0 {
+		t.Errorf("expected footnotes not to be reported as references, got %v", issues)
+	}
 }
 
 func TestCheckRelativeLink(t *testing.T) {
@@ -220,7 +530,7 @@ func TestCheckRelativeLink(t *testing.T) {
 	os.WriteFile(file1, []byte(`[Go to](doc2.md#section-two)`), 0644)
 	os.WriteFile(file2, []byte(`## Section Two`), 0644)
 
-	files := ReadMdFiles(tempDir, testLogger)
+	files, _ := ReadMdFiles(tempDir, WalkerConfig{}, testLogger)
 	links := ExtractLinks(files, testLogger)
 
 	if len(links) != 1 {
@@ -229,7 +539,7 @@ func TestCheckRelativeLink(t *testing.T) {
 
 	link := links[0]
 
-	ok := CheckRelativeLink(link.URL, link.File, files, testLogger)
+	ok := CheckRelativeLink(link.URL, link.File, files, RelativeLinksConfig{}, testLogger)
 	if !ok {
 		t.Errorf("expected link to be valid, got invalid")
 	}
@@ -250,7 +560,7 @@ func TestCheckRelativeLink_ValidWithFragment(t *testing.T) {
 		targetFile: []byte("## My Section"),
 	}
 
-	ok := CheckRelativeLink("doc2.md#my-section", originFile, files, testLogger)
+	ok := CheckRelativeLink("doc2.md#my-section", originFile, files, RelativeLinksConfig{}, testLogger)
 	if !ok {
 		t.Errorf("expected valid link to succeed")
 	}
@@ -266,7 +576,7 @@ func TestCheckRelativeLink_ValidNoFragment(t *testing.T) {
 		targetFile: []byte("Some content"),
 	}
 
-	ok := CheckRelativeLink("doc2.md", originFile, files, testLogger)
+	ok := CheckRelativeLink("doc2.md", originFile, files, RelativeLinksConfig{}, testLogger)
 	if !ok {
 		t.Errorf("expected valid link with no fragment to succeed")
 	}
@@ -278,7 +588,7 @@ func TestCheckRelativeLink_FileNotFound(t *testing.T) {
 	originFile := filepath.Join(dir, "doc1.md")
 	files := map[string][]byte{} // no files
 
-	ok := CheckRelativeLink("doc2.md#header", originFile, files, testLogger)
+	ok := CheckRelativeLink("doc2.md#header", originFile, files, RelativeLinksConfig{}, testLogger)
 	if ok {
 		t.Errorf("expected link to fail due to missing file")
 	}
@@ -294,12 +604,192 @@ func TestCheckRelativeLink_FragmentNotFound(t *testing.T) {
 		targetFile: []byte("## Some Other Section"),
 	}
 
-	ok := CheckRelativeLink("doc2.md#missing", originFile, files, testLogger)
+	ok := CheckRelativeLink("doc2.md#missing", originFile, files, RelativeLinksConfig{}, testLogger)
 	if ok {
 		t.Errorf("expected link to fail due to missing fragment")
 	}
 }
 
+func TestCheckRelativeLink_SameDocumentFragment(t *testing.T) {
+	dir := t.TempDir()
+
+	originFile := filepath.Join(dir, "doc1.md")
+
+	files := map[string][]byte{
+		originFile: []byte("[see below](#usage)\n\n## Usage\n"),
+	}
+
+	if !CheckRelativeLink("#usage", originFile, files, RelativeLinksConfig{}, testLogger) {
+		t.Errorf("expected same-document fragment to resolve")
+	}
+	if CheckRelativeLink("#missing", originFile, files, RelativeLinksConfig{}, testLogger) {
+		t.Errorf("expected missing same-document fragment to fail")
+	}
+}
+
+func TestCheckRelativeLink_Directories(t *testing.T) {
+	dir := t.TempDir()
+
+	originFile := filepath.Join(dir, "doc1.md")
+	apiIndex := filepath.Join(dir, "api", "README.md")
+	for _, sub := range []string{"api", "assets"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string][]byte{
+		originFile: []byte("# Doc"),
+		apiIndex:   []byte("## Endpoints"),
+	}
+
+	for _, url := range []string{"./api/", "api", "api#endpoints", "assets/"} {
+		if !CheckRelativeLink(url, originFile, files, RelativeLinksConfig{}, testLogger) {
+			t.Errorf("expected %q to resolve", url)
+		}
+	}
+	if CheckRelativeLink("assets/#section", originFile, files, RelativeLinksConfig{}, testLogger) {
+		t.Errorf("expected fragment in directory without index to fail")
+	}
+	if CheckRelativeLink("assets/", originFile, files, RelativeLinksConfig{RequireIndex: true}, testLogger) {
+		t.Errorf("expected directory without index to fail when index is required")
+	}
+	if CheckRelativeLink("api/", originFile, files, RelativeLinksConfig{IndexFiles: []string{"index.md"}, RequireIndex: true}, testLogger) {
+		t.Errorf("expected directory without configured index file to fail")
+	}
+}
+
+func TestCheckRelativeLink_AssetsOnDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	docsDir := filepath.Join(dir, "docs")
+	scriptsDir := filepath.Join(dir, "scripts")
+	for _, sub := range []string{docsDir, scriptsDir} {
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		filepath.Join(scriptsDir, "deploy.sh"): "#!/bin/sh",
+		filepath.Join(docsDir, "diagram.svg"):  "<svg/>",
+		filepath.Join(dir, "unloaded.md"):      "## Loaded From Disk",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originFile := filepath.Join(docsDir, "guide.md")
+	files := map[string][]byte{
+		originFile: []byte("# Guide"),
+	}
+
+	for _, url := range []string{"../scripts/deploy.sh", "diagram.svg", "../unloaded.md#loaded-from-disk", "../scripts/deploy.sh#L10"} {
+		if !CheckRelativeLink(url, originFile, files, RelativeLinksConfig{}, testLogger) {
+			t.Errorf("expected %q to resolve", url)
+		}
+	}
+	for _, url := range []string{"../scripts/missing.sh", "../unloaded.md#missing"} {
+		if CheckRelativeLink(url, originFile, files, RelativeLinksConfig{}, testLogger) {
+			t.Errorf("expected %q to fail", url)
+		}
+	}
+}
+
+func TestCheckRelativeLink_RootAndSiteLinks(t *testing.T) {
+	dir := t.TempDir()
+
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.Mkdir(docsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	installFile := filepath.Join(docsDir, "install.md")
+	if err := os.WriteFile(installFile, []byte("## Requirements"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	originFile := filepath.Join(docsDir, "nested.md")
+	files := map[string][]byte{
+		originFile:  []byte("# Nested"),
+		installFile: []byte("## Requirements"),
+	}
+	cfg := RelativeLinksConfig{Root: dir, BaseURL: "https://docs.example.com/"}
+
+	if !CheckRelativeLink("/docs/install.md#requirements", originFile, files, cfg, testLogger) {
+		t.Errorf("expected root link to resolve against the root")
+	}
+	if CheckRelativeLink("/install.md", originFile, files, cfg, testLogger) {
+		t.Errorf("expected root link not to resolve against the file directory")
+	}
+
+	for url, expected := range map[string]string{
+		"https://docs.example.com/docs/install.md":                "/docs/install.md",
+		"https://docs.example.com/docs/install.html#requirements": "/docs/install.md#requirements",
+		"https://docs.example.com/docs/install":                   "/docs/install.md",
+		"https://docs.example.com/docs/":                          "/docs/",
+		"https://docs.example.com/docs/missing":                   "/docs/missing",
+	} {
+		siteLink, ok := SiteLink(url, cfg)
+		if !ok || siteLink != expected {
+			t.Errorf("SiteLink(%q) = %q, %v; want %q", url, siteLink, ok, expected)
+		}
+	}
+	for _, url := range []string{"https://docs.example.com.evil/docs/install", "https://other.example.com/docs/install"} {
+		if _, ok := SiteLink(url, cfg); ok {
+			t.Errorf("expected %q not to be a site link", url)
+		}
+	}
+}
+
+func TestCheckRelativeLink_Normalisation(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"My Doc.md": "## Café Setup",
+		"guide.md":  "# Guide",
+		"100%.md":   "# Percent",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originFile := filepath.Join(dir, "index.md")
+	files := map[string][]byte{originFile: []byte("# Index")}
+
+	for _, url := range []string{"My%20Doc.md", "My%20Doc.md#caf%C3%A9-setup", "guide.md?plain=1", "guide.md?plain=1#guide", "100%.md"} {
+		if !CheckRelativeLink(url, originFile, files, RelativeLinksConfig{}, testLogger) {
+			t.Errorf("expected %q to resolve", url)
+		}
+	}
+	links := ExtractLinks(map[string][]byte{originFile: []byte("[CDN](//cdn.example.com/guide.md)")}, testLogger)
+	if len(links) != 1 || links[0].IsRelative {
+		t.Errorf("expected protocol-relative link to be external, got %+v", links)
+	}
+}
+
+func TestCaseMismatch(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "docs", "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "api", "guide.md"), []byte("# Guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, "docs", "api")
+
+	if _, mismatch := caseMismatch(base, filepath.Join(base, "guide.md")); mismatch {
+		t.Errorf("expected exact spelling to match")
+	}
+	if actual, mismatch := caseMismatch(base, filepath.Join(base, "Guide.md")); !mismatch || actual != filepath.Join(base, "guide.md") {
+		t.Errorf("expected mismatch with guide.md, got %q, %v", actual, mismatch)
+	}
+	if actual, mismatch := caseMismatch(base, filepath.Join(dir, "Docs", "api", "guide.md")); !mismatch || actual != filepath.Join(dir, "docs") {
+		t.Errorf("expected mismatch with docs, got %q, %v", actual, mismatch)
+	}
+}
+
 func TestCheckRelativeLink_InvalidURL(t *testing.T) {
 	dir := t.TempDir()
 
@@ -307,7 +797,7 @@ func TestCheckRelativeLink_InvalidURL(t *testing.T) {
 
 	files := map[string][]byte{}
 
-	ok := CheckRelativeLink("://badurl", originFile, files, testLogger)
+	ok := CheckRelativeLink("://badurl", originFile, files, RelativeLinksConfig{}, testLogger)
 	if ok {
 		t.Errorf("expected link to fail due to invalid URL")
 	}
@@ -321,7 +811,7 @@ func TestReadMdFiles(t *testing.T) {
 		t.Fatalf("failed to write file: %v", err)
 	}
 
-	files := ReadMdFiles(tempDir, testLogger)
+	files, _ := ReadMdFiles(tempDir, WalkerConfig{}, testLogger)
 
 	if len(files) != 1 {
 		t.Errorf("expected 1 file, got %d", len(files))
@@ -343,7 +833,7 @@ func TestReadMdFiles_Basic(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	files := ReadMdFiles(tmp, testLogger)
+	files, _ := ReadMdFiles(tmp, WalkerConfig{}, testLogger)
 
 	if len(files) != 1 {
 		t.Errorf("expected 1 file, got %d", len(files))
@@ -364,7 +854,7 @@ func TestReadMdFiles_SkipDirectory(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	files := ReadMdFiles(tmp, testLogger)
+	files, _ := ReadMdFiles(tmp, WalkerConfig{}, testLogger)
 
 	if len(files) != 1 {
 		t.Errorf("expected 1 file, got %d", len(files))
@@ -381,10 +871,206 @@ func TestReadMdFiles_ErrorReadingFile(t *testing.T) {
 	}
 	defer os.Chmod(badFile, 0644)
 
-	files := ReadMdFiles(tmp, testLogger)
+	files, _ := ReadMdFiles(tmp, WalkerConfig{}, testLogger)
 
 	_, ok := files[badFile]
 	if ok {
 		t.Errorf("expected bad file to be skipped due to read error")
 	}
 }
+
+func TestReadMdFiles_MissingRoot(t *testing.T) {
+	files, err := ReadMdFiles(filepath.Join(t.TempDir(), "missing"), WalkerConfig{}, testLogger)
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected no files, got %d", len(files))
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"node_modules", "node_modules", true},
+		{"node_modules", "web/node_modules", true},
+		{"*.md", "docs/readme.md", true},
+		{"docs/*.md", "docs/readme.md", true},
+		{"docs/*.md", "docs/api/readme.md", false},
+		{"docs/**/*.md", "docs/readme.md", true},
+		{"docs/**/*.md", "docs/api/v1/readme.md", true},
+		{"vendor/**", "vendor/pkg/readme.md", true},
+		{"vendor/**", "vendor", true},
+		{"**/generated", "a/b/generated", true},
+		{"CHANGELOG.?d", "CHANGELOG.md", true},
+		{"[!a]*.md", "a.md", false},
+		{"[!a]*.md", "b.md", true},
+	}
+
+	for _, tt := range tests {
+		glob, err := CompileGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompileGlob(%q): %v", tt.pattern, err)
+		}
+		if got := glob.Match(tt.path); got != tt.want {
+			t.Errorf("%q.Match(%q) = %t, want %t", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := CompileGlob("docs/[abc"); err == nil {
+		t.Errorf("expected error for unterminated character class")
+	}
+}
+
+func TestReadMdFiles_Filters(t *testing.T) {
+	tmp := t.TempDir()
+
+	for _, name := range []string{
+		"readme.md",
+		"guide.markdown",
+		"page.mdx",
+		"image.png",
+		"docs/api.md",
+		"docs/generated/ref.md",
+		"node_modules/pkg/readme.md",
+	} {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# Doc"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exclude, err := CompileGlobs("node_modules", "docs/generated/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := ReadMdFiles(tmp, WalkerConfig{Exclude: exclude}, testLogger)
+
+	if len(files) != 4 {
+		t.Errorf("expected 4 files, got %d: %v", len(files), files)
+	}
+	if _, ok := files[filepath.Join(tmp, "image.png")]; ok {
+		t.Errorf("expected non-markdown file to be skipped")
+	}
+
+	include, err := CompileGlobs("docs/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ = ReadMdFiles(tmp, WalkerConfig{Extensions: []string{".md"}, Include: include, Exclude: exclude}, testLogger)
+
+	if len(files) != 1 {
+		t.Errorf("expected 1 file, got %d: %v", len(files), files)
+	}
+	if _, ok := files[filepath.Join(tmp, "docs", "api.md")]; !ok {
+		t.Errorf("expected docs/api.md to be included")
+	}
+}
+
+func TestReadMdFiles_IgnoreFiles(t *testing.T) {
+	tmp := t.TempDir()
+
+	write := func(name, content string) {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(".gitignore", "build/\n*.draft.md\n# comment\n")
+	write(".marktuatorignore", "/vendor\nhttps://example.com/*\n")
+	write("readme.md", "# Readme")
+	write("notes.draft.md", "# Draft")
+	write("build/out.md", "# Build")
+	write("vendor/lib.md", "# Vendor")
+	write(".git/info.md", "# Git")
+	write("docs/.gitignore", "*.md\n!keep.md\n")
+	write("docs/drop.md", "# Drop")
+	write("docs/keep.md", "# Keep")
+	write("docs/vendor/lib.md", "# Nested vendor")
+
+	files, _ := ReadMdFiles(tmp, WalkerConfig{}, testLogger)
+
+	want := []string{"readme.md", "docs/keep.md"}
+	if len(files) != len(want) {
+		t.Errorf("expected %d files, got %d: %v", len(want), len(files), files)
+	}
+	for _, name := range want {
+		if _, ok := files[filepath.Join(tmp, filepath.FromSlash(name))]; !ok {
+			t.Errorf("expected %s to be read", name)
+		}
+	}
+
+	files, _ = ReadMdFiles(tmp, WalkerConfig{NoIgnore: true}, testLogger)
+	if len(files) != 8 {
+		t.Errorf("expected 8 files without ignore files, got %d", len(files))
+	}
+
+	urlPatterns := ReadIgnoredURLs(tmp, testLogger)
+	if len(urlPatterns) != 1 || urlPatterns[0] != "https://example.com/*" {
+		t.Errorf("unexpected ignored URL patterns: %v", urlPatterns)
+	}
+}
+
+func TestExtractLinks_URLSpans(t *testing.T) {
+	content := []byte("# Title\n\n" +
+		"See [docs](https://example.com/docs \"Docs\") and ![logo]( <https://example.com/logo.png> ).\n" +
+		"Also [reference][ref] and [query](https://example.com/?a=1&amp;b=2).\n" +
+		"<a href=\"https://example.com/html\">html</a>\n" +
+		"[![Build](https://ci.example.com/badge.svg)](https://ci.example.com/build)\n\n" +
+		"[ref]:\n  https://example.com/ref\n")
+	files := map[string][]byte{"spans.md": content}
+
+	links := ExtractLinks(files, testLogger)
+	if len(links) != 7 {
+		t.Fatalf("expected 7 links, got %d", len(links))
+	}
+
+	for _, l := range links {
+		if l.URLEnd == 0 {
+			t.Errorf("expected span of %s to be found", l)
+			continue
+		}
+		if span := string(content[l.URLStart:l.URLEnd]); span != l.URL {
+			t.Errorf("expected span of %s to be %q, got %q", l, l.URL, span)
+		}
+	}
+}
+
+func TestRewriteURLs(t *testing.T) {
+	content := []byte("[a](http://example.com/a)  *[b][ref]*\n\n[ref]: http://example.com/b 'Title'\n")
+	links := ExtractLinks(map[string][]byte{"rewrite.md": content}, testLogger)
+
+	edits := make([]URLEdit, 0)
+	for _, l := range links {
+		edits = append(edits, URLEdit{Link: l, URL: strings.Replace(l.URL, "http://", "https://", 1)})
+	}
+	edits = append(edits, edits[len(edits)-1])
+
+	rewritten, err := RewriteURLs(content, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[a](https://example.com/a)  *[b][ref]*\n\n[ref]: https://example.com/b 'Title'\n"
+	if string(rewritten) != want {
+		t.Errorf("unexpected content:\n%s", rewritten)
+	}
+
+	stale := edits[0]
+	stale.Link.URL = "http://example.com/other"
+	if _, err := RewriteURLs(content, []URLEdit{stale}); err == nil {
+		t.Errorf("expected error for a destination that does not match the file")
+	}
+}
@@ -0,0 +1,310 @@
+package md
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+type ReferenceIssueKind int
+
+const (
+	ReferenceIssueUnusedDefinition ReferenceIssueKind = iota
+	ReferenceIssueUndefinedLabel
+)
+
+func (kind ReferenceIssueKind) String() string {
+	switch kind {
+	case ReferenceIssueUndefinedLabel:
+		return "undefined reference"
+	default:
+		return "unused definition"
+	}
+}
+
+type ReferenceIssue struct {
+	File   string
+	Label  string
+	Kind   ReferenceIssueKind
+	Line   int
+	Column int
+}
+
+func (issue ReferenceIssue) String() string {
+	return fmt.Sprintf("%s [%s] in file %s:%d:%d", issue.Kind, issue.Label, issue.File, issue.Line, issue.Column)
+}
+
+type definition struct {
+	label  string
+	offset int
+}
+
+var (
+	definitionsKey       = parser.NewContextKey()
+	definitionLabelRegex = regexp.MustCompile(`^ {0,3}\[((?:\\.|[^\\\[\]])+)\]:`)
+	fullReferenceRegex   = regexp.MustCompile(`\[((?:\\.|[^\\\[\]])*)\]\[((?:\\.|[^\\\[\]])*)\]`)
+)
+
+// definitionsTransformer wraps goldmark's link reference transformer and
+// records where each link reference definition was found, which goldmark
+// itself does not keep.
+type definitionsTransformer struct{}
+
+func (t definitionsTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc parser.Context) {
+	lines := node.Lines()
+	before := make([]text.Segment, lines.Len())
+	for i := range before {
+		before[i] = lines.At(i)
+	}
+
+	parser.LinkReferenceParagraphTransformer.Transform(node, reader, pc)
+
+	remaining := make(map[int]struct{})
+	if node.Parent() != nil {
+		for i := 0; i < node.Lines().Len(); i++ {
+			remaining[node.Lines().At(i).Start] = struct{}{}
+		}
+	}
+
+	definitions, _ := pc.Get(definitionsKey).([]definition)
+	for _, segment := range before {
+		if _, kept := remaining[segment.Start]; kept {
+			continue
+		}
+		match := definitionLabelRegex.FindSubmatchIndex(segment.Value(reader.Source()))
+		if match == nil {
+			continue
+		}
+		definitions = append(definitions, definition{
+			label:  util.ToLinkReference(segment.Value(reader.Source())[match[2]:match[3]]),
+			offset: segment.Start + match[2] - 1,
+		})
+	}
+	pc.Set(definitionsKey, definitions)
+}
+
+// newParser returns the Markdown parser shared by link extraction and
+// anchor lookup, with footnotes and heading attributes ("{#id}") enabled.
+func newParser() parser.Parser {
+	base := parser.NewParser(
+		parser.WithBlockParsers(parser.DefaultBlockParsers()...),
+		parser.WithInlineParsers(parser.DefaultInlineParsers()...),
+		parser.WithParagraphTransformers(util.Prioritized(definitionsTransformer{}, 100)),
+		parser.WithAttribute(),
+	)
+	return goldmark.New(
+		goldmark.WithParser(base),
+		goldmark.WithExtensions(extension.Footnote),
+	).Parser()
+}
+
+// parseDocument parses content and returns the document together with the
+// link reference definitions it contains, keyed by normalized label. When
+// a label is defined more than once the first definition wins.
+func parseDocument(content []byte) (ast.Node, map[string]definition) {
+	pc := parser.NewContext()
+	doc := newParser().Parse(text.NewReader(content), parser.WithContext(pc))
+
+	definitions := make(map[string]definition)
+	recorded, _ := pc.Get(definitionsKey).([]definition)
+	for _, def := range recorded {
+		if _, exists := definitions[def.label]; !exists {
+			definitions[def.label] = def
+		}
+	}
+	return doc, definitions
+}
+
+// referenceLabel returns the normalized label of a reference-style link
+// node (full, collapsed or shortcut) and false for inline links.
+func referenceLabel(n ast.Node, content []byte) (string, bool) {
+	open := linkOffset(n, content)
+	if open < len(content) && content[open] == '!' {
+		open++
+	}
+
+	closeIndex := closingBracket(content, open)
+	if closeIndex < 0 {
+		return "", false
+	}
+
+	next := closeIndex + 1
+	if next < len(content) && content[next] == '(' {
+		return "", false
+	}
+	if next < len(content) && content[next] == '[' {
+		labelEnd := indexUnescaped(content, next+1, ']')
+		if labelEnd >= 0 {
+			if label := util.ToLinkReference(content[next+1 : labelEnd]); label != "" {
+				return label, true
+			}
+		}
+	}
+	return util.ToLinkReference(content[open+1 : closeIndex]), true
+}
+
+// closingBracket returns the index of the "]" matching the "[" at open,
+// skipping escaped characters, code spans and nested brackets, such as
+// those of an image inside the link text.
+func closingBracket(content []byte, open int) int {
+	depth := 0
+	for i := open; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '`':
+			run := 1
+			for i+run < len(content) && content[i+run] == '`' {
+				run++
+			}
+			if closer := bytes.Index(content[i+run:], bytes.Repeat([]byte{'`'}, run)); closer >= 0 {
+				i += run + closer
+			}
+			i += run - 1
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func indexUnescaped(content []byte, from int, c byte) int {
+	for i := from; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+// FindReferenceIssues reports link reference definitions that are never
+// used and full or collapsed references ("[text][label]", "[label][]")
+// whose label has no definition. Goldmark renders the latter as plain text,
+// so they never show up as links. Undefined shortcut references ("[label]")
+// are indistinguishable from ordinary bracketed text and are not reported.
+func FindReferenceIssues(files map[string][]byte, log *slog.Logger) []ReferenceIssue {
+	issues := make([]ReferenceIssue, 0)
+	for file, content := range files {
+		log.Debug("Check link references in file", slog.String("path", file))
+
+		doc, definitions := parseDocument(content)
+		used := make(map[string]struct{})
+
+		ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			switch n.(type) {
+			case *ast.Link, *ast.Image:
+				if label, ok := referenceLabel(n, content); ok {
+					used[label] = struct{}{}
+				}
+			case *ast.Paragraph, *ast.Heading, *ast.TextBlock:
+				issues = append(issues, findUndefinedReferences(file, n, content, definitions)...)
+			}
+			return ast.WalkContinue, nil
+		})
+
+		for label, def := range definitions {
+			if _, ok := used[label]; ok {
+				continue
+			}
+			line, column := offsetToPosition(content, def.offset)
+			issues = append(issues, ReferenceIssue{
+				File:   file,
+				Label:  label,
+				Kind:   ReferenceIssueUnusedDefinition,
+				Line:   line,
+				Column: column,
+			})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+
+	for _, issue := range issues {
+		log.Debug("Found link reference issue", slog.Any("issue", issue))
+	}
+	return issues
+}
+
+func findUndefinedReferences(file string, block ast.Node, content []byte, definitions map[string]definition) []ReferenceIssue {
+	codeSpans := make([]text.Segment, 0)
+	ast.Walk(block, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			if _, ok := n.(*ast.CodeSpan); ok {
+				first, hasFirst := firstSegment(n)
+				last, hasLast := lastSegment(n)
+				if hasFirst && hasLast {
+					codeSpans = append(codeSpans, text.NewSegment(first.Start, last.Stop))
+				}
+				return ast.WalkSkipChildren, nil
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	issues := make([]ReferenceIssue, 0)
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		value := append([]byte(nil), segment.Value(content)...)
+		for _, span := range codeSpans {
+			for offset := span.Start; offset < span.Stop; offset++ {
+				if offset >= segment.Start && offset < segment.Stop {
+					value[offset-segment.Start] = ' '
+				}
+			}
+		}
+
+		for _, match := range fullReferenceRegex.FindAllSubmatchIndex(value, -1) {
+			if match[0] > 0 && value[match[0]-1] == '\\' {
+				continue
+			}
+			label := util.ToLinkReference(value[match[4]:match[5]])
+			if label == "" {
+				label = util.ToLinkReference(value[match[2]:match[3]])
+			}
+			if label == "" {
+				continue
+			}
+			if _, defined := definitions[label]; defined {
+				continue
+			}
+			line, column := offsetToPosition(content, segment.Start+match[0])
+			issues = append(issues, ReferenceIssue{
+				File:   file,
+				Label:  label,
+				Kind:   ReferenceIssueUndefinedLabel,
+				Line:   line,
+				Column: column,
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,48 @@
+package md
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// URLEdit replaces the destination of Link with URL.
+type URLEdit struct {
+	Link Link
+	URL  string
+}
+
+// RewriteURLs returns content with the destinations of the edited links
+// replaced. Everything outside the destinations is kept byte for byte.
+// Links sharing a reference definition share its span, so an edit may
+// appear several times as long as the new URL is the same.
+func RewriteURLs(content []byte, edits []URLEdit) ([]byte, error) {
+	sorted := make([]URLEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Link.URLStart < sorted[j].Link.URLStart
+	})
+
+	var out bytes.Buffer
+	last := 0
+	for i, edit := range sorted {
+		start, end := edit.Link.URLStart, edit.Link.URLEnd
+		if end == 0 || end > len(content) || string(content[start:end]) != edit.Link.URL {
+			return nil, fmt.Errorf("destination of %s is not found in the file", edit.Link)
+		}
+		if i > 0 && start < last {
+			previous := sorted[i-1]
+			if previous.Link.URLStart == start && previous.URL == edit.URL {
+				continue
+			}
+			return nil, fmt.Errorf("conflicting edits of %s", edit.Link)
+		}
+
+		out.Write(content[last:start])
+		out.WriteString(edit.URL)
+		last = end
+	}
+	out.Write(content[last:])
+
+	return out.Bytes(), nil
+}
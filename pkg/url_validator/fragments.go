@@ -0,0 +1,111 @@
+package url_validator
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	urls "net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const DefaultMaxPageBytes = 5 * 1024 * 1024
+
+// gitHubLineAnchor matches the line anchors of GitHub file views, which are
+// resolved by scripts and never present in the page markup.
+var gitHubLineAnchor = regexp.MustCompile(`^L\d+(C\d+)?(-L\d+(C\d+)?)?$`)
+
+// FetchAnchors downloads the HTML page at url and returns the fragment
+// targets it declares: every id attribute and the name of <a> elements.
+// Non-HTML responses yield a nil set, meaning fragments cannot be verified.
+func FetchAnchors(url string, client http.Client, config LinksValidatorConfig, log *slog.Logger) (map[string]struct{}, error) {
+	log.Debug("Fetch page anchors", slog.String("url", url))
+	client, config = withRule(url, client, config)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, config)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if _, ok := config.AllowedStatuses[resp.StatusCode]; !ok {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		log.Debug("Skip anchors of non-HTML page", slog.String("url", url), slog.String("content_type", mediaType))
+		return nil, nil
+	}
+
+	maxBytes := config.MaxPageBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxPageBytes
+	}
+
+	anchors := make(map[string]struct{})
+	tokenizer := html.NewTokenizer(io.LimitReader(resp.Body, maxBytes))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := tokenizer.TagName()
+		for hasAttr {
+			var key, val []byte
+			key, val, hasAttr = tokenizer.TagAttr()
+			if string(key) == "id" || (string(key) == "name" && string(name) == "a") {
+				anchors[string(val)] = struct{}{}
+			}
+		}
+	}
+
+	return anchors, nil
+}
+
+// HasAnchor reports whether fragment names one of the anchors of the page at
+// url. GitHub prefixes the anchors of rendered Markdown with "user-content-"
+// and resolves line anchors with scripts, so both are accepted for it.
+// Fragments used for client-side routing ("#/path", "#!path") are accepted
+// as is.
+func HasAnchor(url string, fragment string, anchors map[string]struct{}) bool {
+	if fragment == "" || strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "!") {
+		return true
+	}
+	if _, ok := anchors[fragment]; ok {
+		return true
+	}
+
+	if isGitHubURL(url) {
+		if gitHubLineAnchor.MatchString(fragment) {
+			return true
+		}
+		if _, ok := anchors["user-content-"+fragment]; ok {
+			return true
+		}
+		if _, ok := anchors["user-content-"+strings.ToLower(fragment)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isGitHubURL(url string) bool {
+	u, err := urls.Parse(url)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "github.com" || strings.HasSuffix(host, ".github.com")
+}
@@ -0,0 +1,125 @@
+package url_validator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hostLimiter bounds the number of simultaneous requests and, optionally, the
+// request rate for every host.
+type hostLimiter struct {
+	maxPerHost int
+	interval   time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newHostLimiter(maxPerHost int, requestsPerSecond float64) *hostLimiter {
+	limiter := &hostLimiter{
+		maxPerHost: maxPerHost,
+		hosts:      make(map[string]*hostState),
+	}
+	if requestsPerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return limiter
+}
+
+func (l *hostLimiter) host(name string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, exists := l.hosts[name]
+	if !exists {
+		state = &hostState{}
+		if l.maxPerHost > 0 {
+			state.slots = make(chan struct{}, l.maxPerHost)
+		}
+		l.hosts[name] = state
+	}
+	return state
+}
+
+// acquire waits for a free slot and for the rate limit of host and returns
+// the function releasing the slot.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	state := l.host(host)
+
+	release := func() {}
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+			release = sync.OnceFunc(func() { <-state.slots })
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if l.interval > 0 {
+		state.mu.Lock()
+		now := time.Now()
+		start := state.next
+		if start.Before(now) {
+			start = now
+		}
+		state.next = start.Add(l.interval)
+		state.mu.Unlock()
+
+		if wait := start.Sub(now); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return release, nil
+}
+
+// limitedTransport is an http.RoundTripper applying a hostLimiter. The host
+// slot is held until the response body is closed.
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *hostLimiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,96 @@
+package url_validator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Redirect policies: follow redirects up to MaxRedirects hops, or report the
+// redirect response itself, which then has to be an allowed status.
+const (
+	RedirectsFollow   = "follow"
+	RedirectsNoFollow = "no-follow"
+)
+
+const DefaultMaxRedirects = 10
+
+// ErrTooManyRedirects is returned when a redirect chain exceeds the hop
+// limit. Repeating such a request cannot succeed, so it is not retried.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// Redirect is a single hop of a redirect chain.
+type Redirect struct {
+	From       string
+	To         string
+	StatusCode int
+}
+
+// IsPermanent reports whether the hop is a 301 or 308 redirect, meaning the
+// link should be updated to the new location.
+func (redirect Redirect) IsPermanent() bool {
+	return redirect.StatusCode == http.StatusMovedPermanently || redirect.StatusCode == http.StatusPermanentRedirect
+}
+
+// PermanentRedirect returns where the link has permanently moved: the
+// location reached by the permanent hops at the start of the redirect chain.
+// Temporary hops after them are not part of the new address.
+func (result Result) PermanentRedirect() (string, bool) {
+	location := ""
+	for _, redirect := range result.Redirects {
+		if !redirect.IsPermanent() {
+			break
+		}
+		location = redirect.To
+	}
+	return location, location != ""
+}
+
+// FinalURL returns the last location of the redirect chain, or "" when
+// there were no redirects.
+func (result Result) FinalURL() string {
+	if len(result.Redirects) == 0 {
+		return ""
+	}
+	return result.Redirects[len(result.Redirects)-1].To
+}
+
+// checkRedirect implements the redirect policy of config for http.Client.
+func checkRedirect(config LinksValidatorConfig) func(req *http.Request, via []*http.Request) error {
+	maxRedirects := config.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if config.Redirects == RedirectsNoFollow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects: %w", maxRedirects, ErrTooManyRedirects)
+		}
+		return nil
+	}
+}
+
+// redirectChain returns the redirects that led to resp, including resp
+// itself when it is a redirect that was not followed.
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]Redirect{{
+			From:       req.Response.Request.URL.String(),
+			To:         req.URL.String(),
+			StatusCode: req.Response.StatusCode,
+		}}, chain...)
+	}
+
+	if location, err := resp.Location(); err == nil && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		chain = append(chain, Redirect{
+			From:       resp.Request.URL.String(),
+			To:         location.String(),
+			StatusCode: resp.StatusCode,
+		})
+	}
+	return chain
+}
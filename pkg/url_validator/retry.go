@@ -0,0 +1,75 @@
+package url_validator
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRetryDelay    = time.Second
+	DefaultRetryMaxDelay = 30 * time.Second
+)
+
+// isRetryable reports whether a failed check may succeed when repeated:
+// network errors, timeouts, 429 Too Many Requests and 5xx responses.
+func isRetryable(result Result) bool {
+	if result.Err != nil {
+		return !errors.Is(result.Err, context.Canceled) && !errors.Is(result.Err, ErrTooManyRedirects)
+	}
+	return result.StatusCode == http.StatusTooManyRequests || result.StatusCode >= 500
+}
+
+// retryDelay returns the pause before the attempt following the given one:
+// exponential backoff with jitter, or the Retry-After delay when the server
+// asked for a longer one, never exceeding the maximum delay.
+func retryDelay(config LinksValidatorConfig, attempt int, retryAfter time.Duration) time.Duration {
+	base := config.RetryDelay
+	if base <= 0 {
+		base = DefaultRetryDelay
+	}
+	maxDelay := config.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	delay = delay/2 + rand.N(delay/2+1)
+
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
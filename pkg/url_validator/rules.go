@@ -0,0 +1,185 @@
+package url_validator
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// URLRule overrides the checks of URLs matching Pattern. Zero values keep
+// the global settings.
+type URLRule struct {
+	Pattern         *regexp.Regexp
+	Skip            bool
+	AllowedStatuses map[int]struct{}
+	Timeout         time.Duration
+	Method          string
+	Headers         http.Header
+}
+
+// ParseURLRule parses a rule written as a URL pattern followed by options
+// separated by ";":
+//
+//	https://www.linkedin.com/*;status=200,999
+//	https://dash.example.com/*;header=Authorization: Bearer token;timeout=30s
+//	https://api.example.com/*;header=Cookie: a=1\; b=2
+//	re:^https://example\.com/(private|drafts)/;skip
+//
+// A ";" inside the pattern or an option is written as "\;". Patterns are
+// wildcards like ignored URLs unless prefixed with "re:".
+func ParseURLRule(rule string) (URLRule, error) {
+	parts := splitRule(rule)
+
+	pattern := strings.TrimSpace(parts[0])
+	if pattern == "" {
+		return URLRule{}, fmt.Errorf("rule %q has no URL pattern", rule)
+	}
+	var parsed URLRule
+	if expr, isRegexp := strings.CutPrefix(pattern, "re:"); isRegexp {
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return URLRule{}, fmt.Errorf("rule %q: %w", rule, err)
+		}
+		parsed.Pattern = compiled
+	} else {
+		parsed.Pattern = PrepareIgnoredURLs(pattern)[0]
+	}
+
+	for _, option := range parts[1:] {
+		key, value, _ := strings.Cut(option, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "skip":
+			parsed.Skip = true
+		case "status":
+			statuses, err := ParseAllowedStatuses(value)
+			if err != nil {
+				return URLRule{}, fmt.Errorf("rule %q: %w", rule, err)
+			}
+			parsed.AllowedStatuses = statuses
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return URLRule{}, fmt.Errorf("rule %q: %w", rule, err)
+			}
+			parsed.Timeout = timeout
+		case "method":
+			parsed.Method = strings.ToUpper(value)
+			if parsed.Method != http.MethodHead && parsed.Method != http.MethodGet {
+				return URLRule{}, fmt.Errorf("rule %q: unsupported method %q", rule, value)
+			}
+		case "header":
+			name, headerValue, err := parseHeader(value)
+			if err != nil {
+				return URLRule{}, fmt.Errorf("rule %q: %w", rule, err)
+			}
+			if parsed.Headers == nil {
+				parsed.Headers = make(http.Header)
+			}
+			parsed.Headers.Add(name, headerValue)
+		case "":
+		default:
+			return URLRule{}, fmt.Errorf("rule %q: unknown option %q", rule, key)
+		}
+	}
+
+	return parsed, nil
+}
+
+// splitRule splits a rule on the ";" separators that are not escaped as
+// "\;", unescaping the rest.
+func splitRule(rule string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(rule); i++ {
+		switch {
+		case rule[i] == '\\' && i+1 < len(rule) && rule[i+1] == ';':
+			part.WriteByte(';')
+			i++
+		case rule[i] == ';':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(rule[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// ParseHeaders parses request headers written as "Name: value".
+func ParseHeaders(headers ...string) (http.Header, error) {
+	parsed := make(http.Header)
+	for _, header := range headers {
+		name, value, err := parseHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Add(name, value)
+	}
+	return parsed, nil
+}
+
+func parseHeader(header string) (string, string, error) {
+	name, value, found := strings.Cut(header, ":")
+	if !found || strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("header %q must be written as \"Name: value\"", header)
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(value), nil
+}
+
+// ParseURLRules parses rules with ParseURLRule, keeping their order.
+func ParseURLRules(rules ...string) ([]URLRule, error) {
+	parsed := make([]URLRule, 0, len(rules))
+	for _, rule := range rules {
+		r, err := ParseURLRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// ruleFor returns the first rule matching url.
+func (config LinksValidatorConfig) ruleFor(url string) (URLRule, bool) {
+	for _, rule := range config.Rules {
+		if rule.Pattern.MatchString(url) {
+			return rule, true
+		}
+	}
+	return URLRule{}, false
+}
+
+// withRule returns the client and config used to check url, with the
+// settings of the first matching rule applied.
+func withRule(url string, client http.Client, config LinksValidatorConfig) (http.Client, LinksValidatorConfig) {
+	rule, found := config.ruleFor(url)
+	if !found {
+		return client, config
+	}
+
+	if rule.AllowedStatuses != nil {
+		config.AllowedStatuses = rule.AllowedStatuses
+	}
+	if rule.Timeout > 0 {
+		config.Timeout = rule.Timeout
+		client.Timeout = rule.Timeout
+	}
+	if rule.Method != "" {
+		config.Method = rule.Method
+	}
+	if rule.Headers != nil {
+		headers := config.Headers.Clone()
+		if headers == nil {
+			headers = make(http.Header)
+		}
+		for name, values := range rule.Headers {
+			headers[name] = values
+		}
+		config.Headers = headers
+	}
+	return client, config
+}
@@ -1,14 +1,66 @@
 package url_validator
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	urls "net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	DefaultConcurrency  = 10
+	DefaultMaxBodyBytes = 64 * 1024
+)
+
+// MethodAuto requests HEAD first and falls back to GET.
+const MethodAuto = ""
+
 type LinksValidatorConfig struct {
 	AllowedStatuses map[int]struct{}
 	Timeout         time.Duration
+	IgnoredURLs     []*regexp.Regexp
+	// Concurrency is the number of links checked at once,
+	// DefaultConcurrency when not positive.
+	Concurrency int
+	// MaxConcurrentPerHost limits simultaneous requests to a single host and
+	// RequestsPerSecondPerHost their rate. Zero means no limit.
+	MaxConcurrentPerHost     int
+	RequestsPerSecondPerHost float64
+	// Retries is the number of extra attempts after a network error or a
+	// 429/5xx response. The delay between them starts at RetryDelay and
+	// doubles each time, up to RetryMaxDelay.
+	Retries       int
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
+	// Method is the HTTP method used for checks, MethodAuto by default.
+	Method string
+	// MaxBodyBytes is the number of response body bytes read from GET
+	// responses before the connection is closed.
+	MaxBodyBytes int64
+	// CheckFragments enables verification of fragments of external links
+	// against the anchors of the fetched HTML page, reading at most
+	// MaxPageBytes of it.
+	CheckFragments bool
+	MaxPageBytes   int64
+	// Redirects is the redirect policy, RedirectsFollow by default, and
+	// MaxRedirects the number of hops followed, DefaultMaxRedirects when not
+	// positive.
+	Redirects    string
+	MaxRedirects int
+	// Headers are sent with every request.
+	Headers http.Header
+	// Rules override the settings above for matching URLs; the first
+	// matching rule applies.
+	Rules []URLRule
 }
 
 func PrepareAllowedStatuses(statuses ...int) map[int]struct{} {
@@ -22,26 +74,326 @@ func PrepareAllowedStatuses(statuses ...int) map[int]struct{} {
 	return preparedStatuses
 }
 
+// ParseAllowedStatuses parses a comma-separated list of status codes,
+// ranges ("200-299"), classes ("2xx") and negations of any of them ("!204").
+// Negations are applied after the other terms; a list of negations alone
+// allows every other status from 100 to 599.
+func ParseAllowedStatuses(list string) (map[int]struct{}, error) {
+	allowed := make(map[int]struct{})
+	excluded := make([]int, 0)
+	hasAllowed := false
+
+	for _, term := range strings.Split(list, ",") {
+		term = strings.TrimSpace(term)
+		negate := strings.HasPrefix(term, "!")
+		low, high, err := parseStatusTerm(strings.TrimSpace(strings.TrimPrefix(term, "!")))
+		if err != nil {
+			return nil, err
+		}
+		for status := low; status <= high; status++ {
+			if negate {
+				excluded = append(excluded, status)
+			} else {
+				allowed[status] = struct{}{}
+			}
+		}
+		hasAllowed = hasAllowed || !negate
+	}
+
+	if !hasAllowed {
+		for status := 100; status <= 599; status++ {
+			allowed[status] = struct{}{}
+		}
+	}
+	for _, status := range excluded {
+		delete(allowed, status)
+	}
+	return allowed, nil
+}
+
+func parseStatusTerm(term string) (int, int, error) {
+	if class, isClass := strings.CutSuffix(strings.ToLower(term), "xx"); isClass {
+		digit, err := strconv.Atoi(class)
+		if err != nil || len(class) != 1 || digit < 1 || digit > 9 {
+			return 0, 0, fmt.Errorf("invalid status class %q", term)
+		}
+		return digit * 100, digit*100 + 99, nil
+	}
+
+	lowStr, highStr, isRange := strings.Cut(term, "-")
+	low, err := parseStatus(lowStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return low, low, nil
+	}
+	high, err := parseStatus(highStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if low > high {
+		return 0, 0, fmt.Errorf("invalid status range %q", term)
+	}
+	return low, high, nil
+}
+
+func parseStatus(s string) (int, error) {
+	status, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || status < 100 || status > 999 {
+		return 0, fmt.Errorf("invalid status code %q", s)
+	}
+	return status, nil
+}
+
+// PrepareIgnoredURLs compiles URL patterns where "*" matches any sequence
+// of characters and everything else matches literally.
+func PrepareIgnoredURLs(patterns ...string) []*regexp.Regexp {
+
+	preparedPatterns := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		preparedPatterns = append(preparedPatterns, regexp.MustCompile("^"+strings.Join(parts, ".*")+"$"))
+	}
+
+	return preparedPatterns
+}
+
+// NormalizeURL returns a canonical form of url used to detect identical
+// targets: the scheme and host are lowercased, default ports and the
+// fragment are dropped and an empty path becomes "/".
+func NormalizeURL(url string) string {
+	u, err := urls.Parse(url)
+	if err != nil || !u.IsAbs() {
+		return url
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := u.Port()
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return u.String()
+}
+
+func IsIgnoredURL(url string, config LinksValidatorConfig) bool {
+	for _, pattern := range config.IgnoredURLs {
+		if pattern.MatchString(url) {
+			return true
+		}
+	}
+	rule, found := config.ruleFor(url)
+	return found && rule.Skip
+}
+
 func GetClient(config LinksValidatorConfig) http.Client {
 	client := http.Client{
-		Timeout: config.Timeout,
+		Timeout:       config.Timeout,
+		CheckRedirect: checkRedirect(config),
+	}
+
+	if config.MaxConcurrentPerHost > 0 || config.RequestsPerSecondPerHost > 0 {
+		client.Transport = &limitedTransport{
+			base:    http.DefaultTransport,
+			limiter: newHostLimiter(config.MaxConcurrentPerHost, config.RequestsPerSecondPerHost),
+		}
 	}
 
 	return client
 }
 
-func CheckLink(url string, client http.Client, config LinksValidatorConfig, log *slog.Logger) bool {
+// WithScheme returns url with the https scheme when it is protocol-relative
+// (//host/path), so that it can be checked like any other external URL.
+func WithScheme(url string) string {
+	if strings.HasPrefix(url, "//") {
+		return "https:" + url
+	}
+	return url
+}
 
-	log.Debug("Check URL", slog.String("url", url))
-	resp, err := client.Get(url)
+// IsHTTPLink reports whether url is an absolute http or https URL, i.e. one
+// that can be checked with an HTTP request.
+func IsHTTPLink(url string) bool {
+	u, err := urls.Parse(url)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
 
+// Result is the outcome of an URL check.
+type Result struct {
+	OK bool
+	// StatusCode of the last response, zero when no response was received.
+	StatusCode int
+	// Err is the error of the last attempt, if it failed without a response.
+	Err error
+	// Attempts is the number of requests made before the final verdict.
+	Attempts int
+	// Redirects is the redirect chain of the last attempt.
+	Redirects []Redirect
+}
+
+func (result Result) String() string {
+	var verdict string
+	if result.Err != nil {
+		verdict = "error: " + result.Err.Error()
+	} else {
+		verdict = fmt.Sprintf("status %d", result.StatusCode)
+	}
+	description := fmt.Sprintf("%s, attempts: %d", verdict, result.Attempts)
+	if finalURL := result.FinalURL(); finalURL != "" {
+		description += fmt.Sprintf(", redirected to %s", finalURL)
+	}
+	return description
+}
+
+func CheckLink(url string, client http.Client, config LinksValidatorConfig, log *slog.Logger) Result {
+	return checkLink(url, client, config, false, log)
+}
+
+// CheckImageLink checks url like CheckLink and additionally requires the
+// response to declare an image/* content type, if it declares one at all.
+func CheckImageLink(url string, client http.Client, config LinksValidatorConfig, log *slog.Logger) Result {
+	return checkLink(url, client, config, true, log)
+}
+
+func checkLink(url string, client http.Client, config LinksValidatorConfig, expectImage bool, log *slog.Logger) Result {
+	var result Result
+	client, config = withRule(url, client, config)
+
+	for {
+		result.Attempts++
+		retryAfter := checkOnce(url, client, config, expectImage, &result, log)
+
+		if result.OK || !isRetryable(result) || result.Attempts > config.Retries {
+			return result
+		}
+
+		delay := retryDelay(config, result.Attempts, retryAfter)
+		log.Debug("Retry URL check", slog.String("url", url), slog.Int("attempt", result.Attempts), slog.Duration("delay", delay))
+		time.Sleep(delay)
+	}
+}
+
+// checkOnce makes a single check attempt, stores its outcome in result and
+// returns the delay requested by the Retry-After header, if any. Unless a
+// method is configured, HEAD is tried first and GET is used when the server
+// rejects the HEAD method or sends a broken response to it.
+func checkOnce(url string, client http.Client, config LinksValidatorConfig, expectImage bool, result *Result, log *slog.Logger) time.Duration {
+	method := config.Method
+	if method == MethodAuto {
+		method = http.MethodHead
+	}
+
+	retryAfter := request(method, url, client, config, expectImage, result, log)
+
+	if config.Method == MethodAuto && !result.OK && headRejected(*result) {
+		log.Debug("HEAD request failed, fall back to GET", slog.String("url", url), slog.Int("status", result.StatusCode))
+		retryAfter = request(http.MethodGet, url, client, config, expectImage, result, log)
+	}
+
+	return retryAfter
+}
+
+// headRejected reports whether a failed HEAD request may succeed as GET.
+// Servers that do not support HEAD answer with 405 or 501, and some with
+// 400 or 403; a protocol error means the HEAD response itself was broken.
+// Other statuses, timeouts and connection errors would be the same for GET.
+func headRejected(result Result) bool {
+	switch result.StatusCode {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	case 0:
+		return result.Err != nil && !isNetworkError(result.Err)
+	}
+	return false
+}
+
+// isNetworkError reports whether err happened before the server answered:
+// a timeout, a failed DNS lookup or connection, or a stopped redirect chain.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, ErrTooManyRedirects)
+}
+
+func request(method, url string, client http.Client, config LinksValidatorConfig, expectImage bool, result *Result, log *slog.Logger) time.Duration {
+
+	log.Debug("Check URL", slog.String("url", url), slog.String("method", method))
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		result.OK, result.StatusCode, result.Err = false, 0, err
+		return 0
+	}
+	setHeaders(req, config)
+	resp, err := client.Do(req)
+
+	result.Redirects = nil
+	if resp != nil {
+		result.Redirects = redirectChain(resp)
+	}
 	if err != nil {
 		log.Debug("Error while check URL", slog.String("url", url), slog.String("error", err.Error()))
-		return false
+		result.OK, result.StatusCode, result.Err = false, 0, err
+		return 0
 	}
 	defer resp.Body.Close()
+	if config.MaxBodyBytes > 0 {
+		io.CopyN(io.Discard, resp.Body, config.MaxBodyBytes)
+	}
 	log.Debug("Sucess request for URL check", slog.String("url", url), slog.String("status", resp.Status))
 	_, ok := config.AllowedStatuses[resp.StatusCode]
 
-	return ok
+	if ok && expectImage {
+		ok = isImageContentType(resp.Header.Get("Content-Type"))
+		if !ok {
+			log.Debug("Unexpected content type for image", slog.String("url", url), slog.String("content_type", resp.Header.Get("Content-Type")))
+		}
+	}
+
+	result.OK, result.StatusCode, result.Err = ok, resp.StatusCode, nil
+	return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
+
+func setHeaders(req *http.Request, config LinksValidatorConfig) {
+	for name, values := range config.Headers {
+		req.Header[name] = values
+	}
+}
+
+func isImageContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "image/")
 }
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -20,6 +22,30 @@ func TestPrepareAllowedStatuses(t *testing.T) {
 	assert.NotContains(t, statuses, 404)
 }
 
+func TestParseAllowedStatuses(t *testing.T) {
+	statuses, err := ParseAllowedStatuses("2xx, 301-302, !204, 999")
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 102)
+	for _, status := range []int{200, 226, 299, 301, 302, 999} {
+		assert.Contains(t, statuses, status)
+	}
+	for _, status := range []int{204, 300, 303, 404} {
+		assert.NotContains(t, statuses, status)
+	}
+
+	statuses, err = ParseAllowedStatuses("!404,!5xx")
+	assert.NoError(t, err)
+	assert.Contains(t, statuses, 200)
+	assert.Contains(t, statuses, 403)
+	assert.NotContains(t, statuses, 404)
+	assert.NotContains(t, statuses, 503)
+
+	for _, invalid := range []string{"", "ok", "2xxx", "0xx", "299-200", "200-", "42", "!"} {
+		_, err := ParseAllowedStatuses(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
 func TestGetClient(t *testing.T) {
 	config := LinksValidatorConfig{
 		Timeout: 2 * time.Second,
@@ -42,9 +68,10 @@ func TestCheckLink_Success(t *testing.T) {
 	}
 	client := GetClient(config)
 
-	ok := CheckLink(ts.URL, client, config, log)
+	result := CheckLink(ts.URL, client, config, log)
 
-	assert.True(t, ok)
+	assert.True(t, result.OK)
+	assert.Equal(t, 1, result.Attempts)
 }
 
 func TestCheckLink_NotAllowedStatus(t *testing.T) {
@@ -60,9 +87,10 @@ func TestCheckLink_NotAllowedStatus(t *testing.T) {
 	}
 	client := GetClient(config)
 
-	ok := CheckLink(ts.URL, client, config, log)
+	result := CheckLink(ts.URL, client, config, log)
 
-	assert.False(t, ok)
+	assert.False(t, result.OK)
+	assert.Equal(t, http.StatusForbidden, result.StatusCode)
 }
 
 func TestCheckLink_ConnectionError(t *testing.T) {
@@ -73,7 +101,508 @@ func TestCheckLink_ConnectionError(t *testing.T) {
 	}
 	client := GetClient(config)
 
-	ok := CheckLink("http://localhost:0123456789", client, config, log)
+	result := CheckLink("http://localhost:0123456789", client, config, log)
+
+	assert.False(t, result.OK)
+	assert.Error(t, result.Err)
+}
+
+func TestIsHTTPLink(t *testing.T) {
+	assert.True(t, IsHTTPLink("https://example.com"))
+	assert.True(t, IsHTTPLink("http://example.com/path"))
+	assert.False(t, IsHTTPLink("mailto:test@example.com"))
+	assert.False(t, IsHTTPLink("ftp://example.com"))
+	assert.False(t, IsHTTPLink(""))
+	assert.False(t, IsHTTPLink("//example.com"))
+	assert.True(t, IsHTTPLink(WithScheme("//example.com")))
+	assert.Equal(t, "https://example.com/a", WithScheme("//example.com/a"))
+	assert.Equal(t, "/docs", WithScheme("/docs"))
+}
+
+func TestCheckImageLink_ContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         2 * time.Second,
+	}
+	client := GetClient(config)
+
+	assert.True(t, CheckImageLink(ts.URL+"/image.png", client, config, log).OK)
+	assert.False(t, CheckImageLink(ts.URL+"/page.html", client, config, log).OK)
+	assert.True(t, CheckLink(ts.URL+"/page.html", client, config, log).OK)
+}
+
+func TestIsIgnoredURL(t *testing.T) {
+	config := LinksValidatorConfig{
+		IgnoredURLs: PrepareIgnoredURLs("https://example.com/*", "*://localhost*"),
+	}
+
+	assert.True(t, IsIgnoredURL("https://example.com/docs", config))
+	assert.True(t, IsIgnoredURL("http://localhost:8080/", config))
+	assert.False(t, IsIgnoredURL("https://example.org/docs", config))
+	assert.False(t, IsIgnoredURL("https://example.com", config))
+}
+
+func TestGetClient_HostConcurrencyLimit(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses:      PrepareAllowedStatuses(200),
+		Timeout:              2 * time.Second,
+		MaxConcurrentPerHost: 2,
+	}
+	client := GetClient(config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, CheckLink(ts.URL, client, config, log).OK)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxInFlight, 2)
+}
+
+func TestGetClient_HostRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses:          PrepareAllowedStatuses(200),
+		Timeout:                  2 * time.Second,
+		RequestsPerSecondPerHost: 20,
+	}
+	client := GetClient(config)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.True(t, CheckLink(ts.URL, client, config, log).OK)
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestNormalizeURL(t *testing.T) {
+	cases := map[string]string{
+		"HTTPS://Example.COM":                "https://example.com/",
+		"https://example.com:443/docs#intro": "https://example.com/docs",
+		"http://example.com:80/a?b=c":        "http://example.com/a?b=c",
+		"http://example.com:8080/Path":       "http://example.com:8080/Path",
+		"http://[::1]:80/":                   "http://[::1]/",
+		"docs/readme.md":                     "docs/readme.md",
+	}
+
+	for input, expected := range cases {
+		assert.Equal(t, expected, NormalizeURL(input), input)
+	}
+}
+
+func TestCheckLink_RetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         2 * time.Second,
+		Retries:         3,
+		RetryDelay:      time.Millisecond,
+		RetryMaxDelay:   10 * time.Millisecond,
+		Method:          http.MethodGet,
+	}
+	client := GetClient(config)
+
+	result := CheckLink(ts.URL, client, config, log)
+
+	assert.True(t, result.OK)
+	assert.Equal(t, 3, result.Attempts)
+}
+
+func TestCheckLink_DoesNotRetryClientErrors(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         2 * time.Second,
+		Retries:         3,
+		RetryDelay:      time.Millisecond,
+		Method:          http.MethodGet,
+	}
+	client := GetClient(config)
+
+	result := CheckLink(ts.URL, client, config, log)
+
+	assert.False(t, result.OK)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryDelay(t *testing.T) {
+	config := LinksValidatorConfig{
+		RetryDelay:    100 * time.Millisecond,
+		RetryMaxDelay: time.Second,
+	}
+
+	for attempt, maxExpected := range map[int]time.Duration{1: 100, 2: 200, 3: 400, 10: 1000} {
+		delay := retryDelay(config, attempt, 0)
+		assert.LessOrEqual(t, delay, maxExpected*time.Millisecond)
+		assert.GreaterOrEqual(t, delay, maxExpected*time.Millisecond/2)
+	}
+
+	assert.Equal(t, 700*time.Millisecond, retryDelay(config, 1, 700*time.Millisecond))
+	assert.Equal(t, time.Second, retryDelay(config, 1, time.Hour))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("Wed, 01 Jan 2025 12:00:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Wed, 01 Jan 2025 11:00:00 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+}
+
+func TestCheckLink_HeadWithGetFallback(t *testing.T) {
+	var mu sync.Mutex
+	methods := make(map[string][]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods[r.URL.Path] = append(methods[r.URL.Path], r.Method)
+		mu.Unlock()
+
+		if r.URL.Path == "/no-head" && r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(make([]byte, 1024*1024))
+		}
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         2 * time.Second,
+		MaxBodyBytes:    1024,
+	}
+	client := GetClient(config)
+
+	assert.True(t, CheckLink(ts.URL+"/head", client, config, log).OK)
+	assert.True(t, CheckLink(ts.URL+"/no-head", client, config, log).OK)
+
+	config.Method = http.MethodGet
+	assert.True(t, CheckLink(ts.URL+"/get", client, config, log).OK)
+
+	assert.Equal(t, []string{http.MethodHead}, methods["/head"])
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods["/no-head"])
+	assert.Equal(t, []string{http.MethodGet}, methods["/get"])
+}
+
+func TestCheckLink_GetFallbackOnlyWhenHeadRejected(t *testing.T) {
+	var mu sync.Mutex
+	methods := make(map[string][]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods[r.URL.Path] = append(methods[r.URL.Path], r.Method)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+		case "/not-implemented":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotImplemented)
+			}
+		case "/broken-head":
+			if r.Method == http.MethodHead {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Write([]byte("garbage\r\n\r\n"))
+				conn.Close()
+			}
+		}
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         100 * time.Millisecond,
+	}
+	client := GetClient(config)
+
+	assert.False(t, CheckLink(ts.URL+"/missing", client, config, log).OK)
+	assert.False(t, CheckLink(ts.URL+"/slow", client, config, log).OK)
+	assert.True(t, CheckLink(ts.URL+"/not-implemented", client, config, log).OK)
+	assert.True(t, CheckLink(ts.URL+"/broken-head", client, config, log).OK)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{http.MethodHead}, methods["/missing"])
+	assert.Equal(t, []string{http.MethodHead}, methods["/slow"])
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods["/not-implemented"])
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods["/broken-head"])
+}
+
+func TestFetchAnchors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><body><h2 id="install">Install</h2><a name="legacy"></a><div name="ignored"></div></body></html>`))
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		}
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         2 * time.Second,
+	}
+	client := GetClient(config)
+
+	anchors, err := FetchAnchors(ts.URL+"/page", client, config, log)
+	assert.NoError(t, err)
+	assert.Contains(t, anchors, "install")
+	assert.Contains(t, anchors, "legacy")
+	assert.NotContains(t, anchors, "ignored")
+
+	anchors, err = FetchAnchors(ts.URL+"/file.pdf", client, config, log)
+	assert.NoError(t, err)
+	assert.Nil(t, anchors)
+}
+
+func TestHasAnchor(t *testing.T) {
+	anchors := map[string]struct{}{
+		"install":                  {},
+		"user-content-quick-start": {},
+	}
+
+	assert.True(t, HasAnchor("https://example.com/", "install", anchors))
+	assert.False(t, HasAnchor("https://example.com/", "missing", anchors))
+	assert.False(t, HasAnchor("https://example.com/", "quick-start", anchors))
+	assert.True(t, HasAnchor("https://github.com/org/repo", "quick-start", anchors))
+	assert.True(t, HasAnchor("https://github.com/org/repo/blob/main/main.go", "L10-L20", anchors))
+	assert.True(t, HasAnchor("https://example.com/app", "/settings", anchors))
+}
+
+func TestParseURLRule(t *testing.T) {
+	rule, err := ParseURLRule("https://dash.example.com/*;status=200,401;timeout=30s;method=get;header=Authorization: Bearer token;header=X-Team: docs")
+	assert.NoError(t, err)
+	assert.True(t, rule.Pattern.MatchString("https://dash.example.com/boards/1"))
+	assert.False(t, rule.Pattern.MatchString("https://example.com/"))
+	assert.Equal(t, PrepareAllowedStatuses(200, 401), rule.AllowedStatuses)
+	assert.Equal(t, 30*time.Second, rule.Timeout)
+	assert.Equal(t, http.MethodGet, rule.Method)
+	assert.Equal(t, "Bearer token", rule.Headers.Get("Authorization"))
+	assert.Equal(t, "docs", rule.Headers.Get("X-Team"))
+	assert.False(t, rule.Skip)
+
+	rule, err = ParseURLRule(`https://api.example.com/*;header=Cookie: a=1\; b=2;timeout=5s`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1; b=2", rule.Headers.Get("Cookie"))
+	assert.Equal(t, 5*time.Second, rule.Timeout)
+
+	rule, err = ParseURLRule(`re:^https://example\.com/a\;b;skip`)
+	assert.NoError(t, err)
+	assert.True(t, rule.Pattern.MatchString("https://example.com/a;b"))
+
+	rule, err = ParseURLRule(`re:^https://example\.com/(private|drafts)/;skip`)
+	assert.NoError(t, err)
+	assert.True(t, rule.Skip)
+	assert.True(t, rule.Pattern.MatchString("https://example.com/drafts/1"))
+
+	for _, invalid := range []string{";skip", "https://x/*;status=ok", "https://x/*;timeout=1", "https://x/*;method=POST", "https://x/*;header=NoColon", "https://x/*;retry=3", "re:(;skip"} {
+		_, err := ParseURLRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("Authorization: Bearer token", "Accept: text/html, */*", "X-Empty:")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+	assert.Equal(t, "text/html, */*", headers.Get("Accept"))
+	assert.Equal(t, []string{""}, headers.Values("X-Empty"))
+
+	_, err = ParseHeaders("NoColon")
+	assert.Error(t, err)
+	_, err = ParseHeaders(": value")
+	assert.Error(t, err)
+}
+
+func TestCheckLink_Rules(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/linkedin":
+			w.WriteHeader(999)
+		case r.URL.Path == "/dashboard" && r.Header.Get("Authorization") != "Bearer token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	rules, err := ParseURLRules(
+		ts.URL+"/linkedin;status=200,999",
+		ts.URL+"/dashboard;header=Authorization: Bearer token",
+		ts.URL+"/slow;timeout=2s",
+		ts.URL+"/private/*;skip",
+		ts.URL+"/*;status=204",
+	)
+	assert.NoError(t, err)
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Timeout:         50 * time.Millisecond,
+		Method:          http.MethodGet,
+		Rules:           rules,
+	}
+	client := GetClient(config)
+
+	assert.True(t, CheckLink(ts.URL+"/linkedin", client, config, log).OK)
+	assert.True(t, CheckLink(ts.URL+"/dashboard", client, config, log).OK)
+	assert.True(t, CheckLink(ts.URL+"/slow", client, config, log).OK)
+	assert.False(t, CheckLink(ts.URL+"/other", client, config, log).OK)
+	assert.True(t, IsIgnoredURL(ts.URL+"/private/page", config))
+	assert.False(t, IsIgnoredURL(ts.URL+"/other", config))
+}
+
+func TestCheckLink_Redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/new", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/new", http.StatusFound))
+	mux.Handle("/login-wall", http.RedirectHandler("/temporary", http.StatusMovedPermanently))
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		Method:          http.MethodGet,
+		MaxRedirects:    3,
+	}
+	client := GetClient(config)
+
+	result := CheckLink(ts.URL+"/old", client, config, log)
+	assert.True(t, result.OK)
+	assert.Equal(t, []Redirect{
+		{From: ts.URL + "/old", To: ts.URL + "/moved", StatusCode: http.StatusMovedPermanently},
+		{From: ts.URL + "/moved", To: ts.URL + "/new", StatusCode: http.StatusPermanentRedirect},
+	}, result.Redirects)
+	location, moved := result.PermanentRedirect()
+	assert.True(t, moved)
+	assert.Equal(t, ts.URL+"/new", location)
+
+	result = CheckLink(ts.URL+"/temporary", client, config, log)
+	assert.True(t, result.OK)
+	_, moved = result.PermanentRedirect()
+	assert.False(t, moved)
+
+	result = CheckLink(ts.URL+"/login-wall", client, config, log)
+	location, moved = result.PermanentRedirect()
+	assert.True(t, moved)
+	assert.Equal(t, ts.URL+"/temporary", location)
+	assert.Equal(t, ts.URL+"/new", result.FinalURL())
+
+	result = CheckLink(ts.URL+"/loop", client, config, log)
+	assert.False(t, result.OK)
+	assert.Error(t, result.Err)
+	assert.Len(t, result.Redirects, 4)
+
+	config.Redirects = RedirectsNoFollow
+	config.AllowedStatuses = PrepareAllowedStatuses(200, 301)
+	client = GetClient(config)
+
+	result = CheckLink(ts.URL+"/old", client, config, log)
+	assert.True(t, result.OK)
+	assert.Equal(t, http.StatusMovedPermanently, result.StatusCode)
+	assert.Equal(t, []Redirect{{From: ts.URL + "/old", To: ts.URL + "/moved", StatusCode: http.StatusMovedPermanently}}, result.Redirects)
+	assert.False(t, CheckLink(ts.URL+"/temporary", client, config, log).OK)
+}
+
+func TestCheckLink_RedirectLimitIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	config := LinksValidatorConfig{
+		AllowedStatuses: PrepareAllowedStatuses(200),
+		MaxRedirects:    3,
+		Retries:         2,
+		RetryDelay:      time.Millisecond,
+	}
+
+	result := CheckLink(ts.URL+"/loop", GetClient(config), config, log)
 
-	assert.False(t, ok)
+	assert.False(t, result.OK)
+	assert.ErrorIs(t, result.Err, ErrTooManyRedirects)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, int32(4), requests.Load())
 }